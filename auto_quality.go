@@ -0,0 +1,226 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+const (
+	autoQualityMin = 10
+
+	// SSIM window size and step, in pixels
+	ssimWindow = 8
+	ssimStep   = 4
+)
+
+var ErrScoreUnreachable = fmt.Errorf("image can't be encoded with the required score")
+
+// Lab channels weights of the SSIM score. Lightness errors are much more visible than colour ones.
+var ssimWeights = [3]float64{0.6, 0.2, 0.2}
+
+type labPixels struct {
+	width, height int
+	// pix holds L, a and b values of every pixel
+	pix []float32
+}
+
+// SaveAutoQuality encodes the image with the lowest quality which SSIM against the image, computed in Lab,
+// is at least targetScore, e.g. 0.98. Quality of opts is the highest one tried, lossless is turned off.
+// The output of the highest quality is returned with ErrScoreUnreachable if it doesn't meet the target.
+// The image is left decoded in memory, like after CopyMemory, and may be encoded again.
+func (img *VipsImage) SaveAutoQuality(imgType ImageFormat, targetScore float64, opts encodeConfig) (int, []byte, error) {
+	switch imgType {
+	case JPEG, WEBP, AVIF:
+	default:
+		return 0, nil, ErrUnsupportedImageFormat
+	}
+
+	if targetScore <= 0 || targetScore > 1 {
+		return 0, nil, fmt.Errorf("invalid target score value %g", targetScore)
+	}
+
+	maxQuality := int(opts.quality)
+	if maxQuality < autoQualityMin {
+		return 0, nil, fmt.Errorf("invalid quality value %d", maxQuality)
+	}
+
+	// The image is read to measure it and then on every encoding, it's decoded into memory in place
+	if err := img.CopyMemory(); err != nil {
+		return 0, nil, err
+	}
+
+	ref, err := img.labPixels()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	opts.Lossless(false)
+
+	return searchLowestQuality(autoQualityMin, maxQuality, targetScore, func(q int) (float64, []byte, error) {
+		opts.Quality(q)
+
+		buf, err := img.Save(imgType, opts)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		decoded, err := Load(buf)
+		if err != nil {
+			return 0, nil, err
+		}
+		defer decoded.Clear()
+
+		pixels, err := decoded.labPixels()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		score, err := ssim(ref, pixels)
+
+		return score, buf, err
+	})
+}
+
+// searchLowestQuality finds the lowest quality in [lo, hi] which score is at least target
+func searchLowestQuality(lo, hi int, target float64, encode func(int) (float64, []byte, error)) (int, []byte, error) {
+	score, buf, err := encode(hi)
+	if err != nil {
+		return 0, nil, err
+	}
+	if score < target {
+		return hi, buf, ErrScoreUnreachable
+	}
+
+	best, bestBuf := hi, buf
+
+	hi--
+	for lo <= hi {
+		mid := (lo + hi) / 2
+
+		if score, buf, err = encode(mid); err != nil {
+			return 0, nil, err
+		}
+
+		if score >= target {
+			if len(buf) <= len(bestBuf) {
+				best, bestBuf = mid, buf
+			}
+			hi = mid - 1
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	return best, bestBuf, nil
+}
+
+func (img *VipsImage) labPixels() (labPixels, error) {
+	size := C.size_t(0)
+
+	ptr := C.vips_lab_pixels_go(img.img, &size)
+	if ptr == nil {
+		return labPixels{}, vipsError("SaveAutoQuality")
+	}
+	defer C.g_free(C.gpointer(ptr))
+
+	pix := make([]float32, int(size)/4)
+	copy(pix, unsafe.Slice((*float32)(unsafe.Pointer(ptr)), len(pix)))
+
+	return labPixels{width: img.Width(), height: img.Height(), pix: pix}, nil
+}
+
+// ssim returns the weighted mean SSIM of the Lab channels over sliding windows
+func ssim(x, y labPixels) (float64, error) {
+	if x.width != y.width || x.height != y.height || len(x.pix) != len(y.pix) {
+		return 0, fmt.Errorf("can't compare %dx%d image with %dx%d one", x.width, x.height, y.width, y.height)
+	}
+
+	if len(x.pix) != x.width*x.height*3 {
+		return 0, fmt.Errorf("invalid number of Lab values %d for %dx%d image", len(x.pix), x.width, x.height)
+	}
+
+	if len(x.pix) == 0 {
+		return 1, nil
+	}
+
+	winW, winH := ssimWindow, ssimWindow
+	if x.width < winW {
+		winW = x.width
+	}
+	if x.height < winH {
+		winH = x.height
+	}
+
+	// Stabilizing constants for the dynamic range of L, which is 100
+	const c1, c2 = (0.01 * 100) * (0.01 * 100), (0.03 * 100) * (0.03 * 100)
+
+	var total float64
+	windows := 0
+
+	for top := 0; top+winH <= x.height; top += ssimStep {
+		for left := 0; left+winW <= x.width; left += ssimStep {
+			var score float64
+
+			for ch := 0; ch < 3; ch++ {
+				var sumX, sumY, sumXX, sumYY, sumXY float64
+
+				for row := top; row < top+winH; row++ {
+					for col := left; col < left+winW; col++ {
+						i := (row*x.width+col)*3 + ch
+						vx, vy := float64(x.pix[i]), float64(y.pix[i])
+
+						sumX += vx
+						sumY += vy
+						sumXX += vx * vx
+						sumYY += vy * vy
+						sumXY += vx * vy
+					}
+				}
+
+				n := float64(winW * winH)
+				muX, muY := sumX/n, sumY/n
+				varX, varY := sumXX/n-muX*muX, sumYY/n-muY*muY
+				covXY := sumXY/n - muX*muY
+
+				score += ssimWeights[ch] * ((2*muX*muY + c1) * (2*covXY + c2)) /
+					((muX*muX + muY*muY + c1) * (varX + varY + c2))
+			}
+
+			total += score
+			windows++
+		}
+	}
+
+	return total / float64(windows), nil
+}
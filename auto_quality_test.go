@@ -0,0 +1,123 @@
+package libvips_go
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func gradientLab(w, h int, shift float32) labPixels {
+	pix := make([]float32, w*h*3)
+	for i := 0; i < w*h; i++ {
+		pix[i*3] = float32(i%w*100/w) + shift
+		pix[i*3+1] = float32(i/w) - 20
+		pix[i*3+2] = 10
+	}
+
+	return labPixels{width: w, height: h, pix: pix}
+}
+
+func TestSSIM(t *testing.T) {
+	ref := gradientLab(32, 24, 0)
+
+	noisy := gradientLab(32, 24, 0)
+	for i := 0; i < len(noisy.pix); i += 3 {
+		if i/3%2 == 0 {
+			noisy.pix[i] += 15
+		} else {
+			noisy.pix[i] -= 15
+		}
+	}
+
+	tests := []struct {
+		name    string
+		x, y    labPixels
+		wantMin float64
+		wantMax float64
+		wantErr bool
+	}{
+		{"Identical", ref, ref, 1, 1, false},
+		{"IdenticalTiny", gradientLab(3, 2, 0), gradientLab(3, 2, 0), 1, 1, false},
+		{"SlightShift", ref, gradientLab(32, 24, 1), 0.95, 1, false},
+		{"Noisy", ref, noisy, 0, 0.9, false},
+		{"SizeMismatch", ref, gradientLab(24, 32, 0), 0, 0, true},
+		{"ShortBuffer", labPixels{width: 2, height: 2, pix: make([]float32, 3)}, labPixels{width: 2, height: 2, pix: make([]float32, 3)}, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ssim(tt.x, tt.y)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ssim() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (got < tt.wantMin-1e-9 || got > tt.wantMax+1e-9 || math.IsNaN(got)) {
+				t.Errorf("ssim() got = %v, want in [%v, %v]", got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestSearchLowestQuality(t *testing.T) {
+	// The score grows with quality and reaches 1 at quality 100
+	encode := func(q int) (float64, []byte, error) {
+		return float64(q) / 100, make([]byte, q), nil
+	}
+
+	tests := []struct {
+		name    string
+		target  float64
+		want    int
+		wantErr error
+	}{
+		{"LowestMeetsTarget", 0.05, 10, nil},
+		{"Middle", 0.5, 50, nil},
+		{"HighestOnly", 0.95, 95, nil},
+		{"Unreachable", 0.99, 95, ErrScoreUnreachable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, buf, err := searchLowestQuality(10, 95, tt.target, encode)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("searchLowestQuality() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || len(buf) != got {
+				t.Errorf("searchLowestQuality() got = %d with %d bytes, want %d", got, len(buf), tt.want)
+			}
+		})
+	}
+}
+
+func TestVipsImage_SaveAutoQuality(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  float64
+		wantErr error
+	}{
+		{"Low", 0.5, nil},
+		{"High", 0.98, nil},
+		{"Unreachable", 1, ErrScoreUnreachable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The image is read sequentially, as loaded
+			img := loadFixture(t, "wiki_a4.png")
+
+			q, buf, err := img.SaveAutoQuality(JPEG, tt.target, DefaultEncodeConfig)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SaveAutoQuality() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if q < autoQualityMin || q > int(DefaultEncodeConfig.quality) {
+				t.Errorf("SaveAutoQuality() quality = %d, want in [%d, %d]", q, autoQualityMin, DefaultEncodeConfig.quality)
+			}
+
+			if FormatByMagicNumber(buf) != JPEG {
+				t.Errorf("SaveAutoQuality() wrote %s, want JPEG", FormatByMagicNumber(buf))
+			}
+
+			// The image stays usable
+			if _, err = img.Save(PNG, DefaultEncodeConfig); err != nil {
+				t.Errorf("Save() after SaveAutoQuality() error = %v", err)
+			}
+		})
+	}
+}
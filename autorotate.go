@@ -0,0 +1,88 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+
+// AutoRotate applies the EXIF orientation, so the image is displayed upright without it,
+// and removes the orientation tag
+func (img *VipsImage) AutoRotate() error {
+	angle, flip := orientationTransform(img.Orientation())
+	if angle == 0 && !flip {
+		return nil
+	}
+
+	if angle != 0 {
+		// The rotation reads the rows out of order, the loaders give sequential access only
+		if err := img.CopyMemory(); err != nil {
+			return err
+		}
+
+		if err := img.Rotate(angle); err != nil {
+			return err
+		}
+	}
+
+	if flip {
+		if err := img.Flip(); err != nil {
+			return err
+		}
+	}
+
+	// Both operations produce a new image, so the tag is removed from this image only
+	C.vips_autorot_remove_angle(img.img)
+
+	return nil
+}
+
+// orientationTransform returns the clockwise rotation and the following horizontal flip which
+// undo the EXIF orientation. Flip of orientation 4 is vertical, which is the 180 degrees rotation
+// flipped horizontally. Unknown orientations are left as is.
+func orientationTransform(orientation int) (int, bool) {
+	switch orientation {
+	case 2:
+		return 0, true
+	case 3:
+		return 180, false
+	case 4:
+		return 180, true
+	case 5:
+		return 90, true
+	case 6:
+		return 90, false
+	case 7:
+		return 270, true
+	case 8:
+		return 270, false
+	}
+
+	return 0, false
+}
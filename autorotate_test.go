@@ -0,0 +1,103 @@
+package libvips_go
+
+import (
+	"strconv"
+	"testing"
+)
+
+// transform applies the clockwise rotation and the horizontal flip to the point of w x h image
+func transform(x, y, w, h, angle int, flip bool) (int, int, int, int) {
+	for ; angle > 0; angle -= 90 {
+		x, y, w, h = h-1-y, x, h, w
+	}
+
+	if flip {
+		x = w - 1 - x
+	}
+
+	return x, y, w, h
+}
+
+func TestOrientationTransform(t *testing.T) {
+	// Where the top left corner of the upright 3x2 image is stored for every orientation,
+	// and the stored dimensions
+	stored := []struct {
+		orientation int
+		x, y, w, h  int
+	}{
+		{1, 0, 0, 3, 2},
+		{2, 2, 0, 3, 2},
+		{3, 2, 1, 3, 2},
+		{4, 0, 1, 3, 2},
+		{5, 0, 0, 2, 3},
+		{6, 0, 2, 2, 3},
+		{7, 1, 2, 2, 3},
+		{8, 1, 0, 2, 3},
+	}
+	for _, tt := range stored {
+		t.Run(strconv.Itoa(tt.orientation), func(t *testing.T) {
+			angle, flip := orientationTransform(tt.orientation)
+
+			x, y, w, h := transform(tt.x, tt.y, tt.w, tt.h, angle, flip)
+			if x != 0 || y != 0 || w != 3 || h != 2 {
+				t.Errorf("orientationTransform(%d) = %d, %v moves the corner to %d,%d of %dx%d, want 0,0 of 3x2",
+					tt.orientation, angle, flip, x, y, w, h)
+			}
+		})
+	}
+
+	for _, o := range []int{0, 9, -1} {
+		if angle, flip := orientationTransform(o); angle != 0 || flip {
+			t.Errorf("orientationTransform(%d) = %d, %v, want no transform", o, angle, flip)
+		}
+	}
+}
+
+// orientedJPEG stores the 3x2 fixture with the orientation tag
+func orientedJPEG(t *testing.T, orientation int) []byte {
+	t.Helper()
+
+	img := loadFixture(t, "blank.png")
+	if err := img.CopyMemory(); err != nil {
+		t.Fatal(err)
+	}
+	img.SetInt("orientation", orientation)
+
+	buf, err := img.Save(JPEG, DefaultEncodeConfig)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	return buf
+}
+
+func TestLoadWithOptions_AutoRotate(t *testing.T) {
+	startVips(t)
+
+	for orientation := 1; orientation <= 8; orientation++ {
+		t.Run(strconv.Itoa(orientation), func(t *testing.T) {
+			buf := orientedJPEG(t, orientation)
+
+			img, err := LoadWithOptions(buf, LoadOptions{AutoRotate: true})
+			if err != nil {
+				t.Fatalf("LoadWithOptions() error = %v", err)
+			}
+			defer img.Clear()
+
+			angle, flip := orientationTransform(orientation)
+			_, _, w, h := transform(0, 0, 3, 2, angle, flip)
+			if img.Width() != w || img.Height() != h {
+				t.Errorf("AutoRotate() got = %dx%d, want %dx%d", img.Width(), img.Height(), w, h)
+			}
+
+			if o := img.Orientation(); o > 1 {
+				t.Errorf("AutoRotate() left orientation %d", o)
+			}
+
+			// The pixels of the sequentially loaded image are read only now
+			if _, err = img.Save(PNG, DefaultEncodeConfig); err != nil {
+				t.Errorf("Save() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,452 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"unsafe"
+)
+
+const (
+	bmpFileHeaderSize = 14
+	bmpCoreHeaderSize = 12
+	bmpV4HeaderSize   = 108
+
+	bmpRGB            = 0
+	bmpRLE8           = 1
+	bmpRLE4           = 2
+	bmpBitfields      = 3
+	bmpAlphaBitfields = 6
+
+	// Compressed bitmaps can claim any dimensions, the limit keeps the decoded image under 256 MB
+	bmpMaxPixels = 1 << 26
+)
+
+// loadBMP decodes BMP in Go, it's used when libvips is built without image magick. The size limits of opts
+// are checked against the header before the pixels are decoded.
+func loadBMP(buf []byte, opts LoadOptions) (*VipsImage, error) {
+	hdr, err := parseBMPHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = opts.checkSize(hdr.w, hdr.h); err != nil {
+		return nil, err
+	}
+
+	w, h, rgba, err := decodeBMP(buf, hdr)
+	if err != nil {
+		return nil, err
+	}
+
+	img := C.vips_image_new_from_rgba_go(unsafe.Pointer(&rgba[0]), C.size_t(len(rgba)), C.int(w), C.int(h))
+	if img == nil {
+		return nil, vipsError("loadBMP")
+	}
+
+	return &VipsImage{img}, nil
+}
+
+// saveBMP encodes BMP in Go, it's used when libvips is built without image magick
+func (img *VipsImage) saveBMP() ([]byte, error) {
+	rgba, err := img.rgbaPixels()
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeBMP(img.Width(), img.Height(), rgba), nil
+}
+
+type bmpChannel struct {
+	mask  uint32
+	shift int
+	bits  int
+}
+
+func newBMPChannel(mask uint32) bmpChannel {
+	if mask == 0 {
+		return bmpChannel{}
+	}
+
+	shift := bits.TrailingZeros32(mask)
+
+	return bmpChannel{mask: mask, shift: shift, bits: bits.OnesCount32(mask >> shift)}
+}
+
+// value scales the masked bits to 8 bits
+func (c bmpChannel) value(px uint32) byte {
+	if c.mask == 0 {
+		return 0
+	}
+
+	v := (px & c.mask) >> c.shift
+	if c.bits >= 8 {
+		return byte(v >> (c.bits - 8))
+	}
+
+	return byte(v * 255 / (1<<c.bits - 1))
+}
+
+type bmpHeader struct {
+	w, h, bpp   int
+	headerSize  int
+	dataOffset  int
+	colors      int
+	compression uint32
+	topDown     bool
+}
+
+// parseBMPHeader reads the file and DIB headers, nothing is decoded
+func parseBMPHeader(buf []byte) (bmpHeader, error) {
+	if len(buf) < bmpFileHeaderSize+bmpCoreHeaderSize || buf[0] != 'B' || buf[1] != 'M' {
+		return bmpHeader{}, fmt.Errorf("%w: invalid bitmap header", ErrCorruptImage)
+	}
+
+	dataOffset := int(binary.LittleEndian.Uint32(buf[10:]))
+	dib := buf[bmpFileHeaderSize:]
+	headerSize := int(binary.LittleEndian.Uint32(dib))
+
+	var w, h, bpp int
+	var compression uint32
+	var colors int
+
+	switch {
+	case headerSize == bmpCoreHeaderSize:
+		w = int(binary.LittleEndian.Uint16(dib[4:]))
+		h = int(binary.LittleEndian.Uint16(dib[6:]))
+		bpp = int(binary.LittleEndian.Uint16(dib[10:]))
+	case headerSize >= dibHeaderSize && headerSize <= len(dib):
+		w = int(int32(binary.LittleEndian.Uint32(dib[4:])))
+		h = int(int32(binary.LittleEndian.Uint32(dib[8:])))
+		bpp = int(binary.LittleEndian.Uint16(dib[14:]))
+		compression = binary.LittleEndian.Uint32(dib[16:])
+		colors = int(binary.LittleEndian.Uint32(dib[32:]))
+	default:
+		return bmpHeader{}, fmt.Errorf("%w: invalid bitmap header size %d", ErrCorruptImage, headerSize)
+	}
+
+	// Positive height means the rows are stored bottom-up
+	topDown := h < 0
+	if topDown {
+		h = -h
+	}
+
+	if w <= 0 || h <= 0 || w*h > bmpMaxPixels {
+		return bmpHeader{}, fmt.Errorf("%w: invalid bitmap dimensions %dx%d", ErrCorruptImage, w, h)
+	}
+
+	if dataOffset <= 0 || dataOffset > len(buf) {
+		return bmpHeader{}, fmt.Errorf("%w: invalid bitmap data offset %d", ErrCorruptImage, dataOffset)
+	}
+
+	return bmpHeader{
+		w:           w,
+		h:           h,
+		bpp:         bpp,
+		headerSize:  headerSize,
+		dataOffset:  dataOffset,
+		colors:      colors,
+		compression: compression,
+		topDown:     topDown,
+	}, nil
+}
+
+// decodeBMP decodes 1, 4, 8, 16, 24 and 32-bit bitmaps, including RLE and bitfields ones, into RGBA.
+// hdr is the parsed header of buf.
+func decodeBMP(buf []byte, hdr bmpHeader) (int, int, []byte, error) {
+	w, h, bpp, headerSize, dataOffset := hdr.w, hdr.h, hdr.bpp, hdr.headerSize, hdr.dataOffset
+	colors, compression, topDown := hdr.colors, hdr.compression, hdr.topDown
+	dib := buf[bmpFileHeaderSize:]
+	pos := bmpFileHeaderSize + headerSize
+
+	var r, g, b, a bmpChannel
+
+	switch compression {
+	case bmpRGB:
+		switch bpp {
+		case 1, 4, 8, 24, 32:
+		case 16:
+			r, g, b = newBMPChannel(0x7c00), newBMPChannel(0x03e0), newBMPChannel(0x001f)
+		default:
+			return 0, 0, nil, fmt.Errorf("unsupported bitmap depth %d", bpp)
+		}
+
+		if bpp == 32 {
+			r, g, b = newBMPChannel(0xff0000), newBMPChannel(0xff00), newBMPChannel(0xff)
+		}
+	case bmpRLE8, bmpRLE4:
+		if bpp != 8 && compression == bmpRLE8 || bpp != 4 && compression == bmpRLE4 || topDown {
+			return 0, 0, nil, fmt.Errorf("%w: invalid RLE bitmap", ErrCorruptImage)
+		}
+	case bmpBitfields, bmpAlphaBitfields:
+		if bpp != 16 && bpp != 32 {
+			return 0, 0, nil, fmt.Errorf("%w: invalid bitfields bitmap depth %d", ErrCorruptImage, bpp)
+		}
+
+		// Masks follow BITMAPINFOHEADER, later headers include them
+		masks := dib[dibHeaderSize:]
+		n := 3
+		if compression == bmpAlphaBitfields || headerSize >= dibHeaderSize+16 {
+			n = 4
+		}
+
+		if len(masks) < n*4 {
+			return 0, 0, nil, fmt.Errorf("%w: truncated bitmap masks", ErrCorruptImage)
+		}
+
+		r = newBMPChannel(binary.LittleEndian.Uint32(masks[0:]))
+		g = newBMPChannel(binary.LittleEndian.Uint32(masks[4:]))
+		b = newBMPChannel(binary.LittleEndian.Uint32(masks[8:]))
+		if n == 4 {
+			a = newBMPChannel(binary.LittleEndian.Uint32(masks[12:]))
+		}
+
+		if headerSize == dibHeaderSize {
+			pos += n * 4
+		}
+	default:
+		return 0, 0, nil, fmt.Errorf("unsupported bitmap compression %d", compression)
+	}
+
+	var palette [][3]byte
+
+	if bpp <= 8 {
+		entrySize := 4
+		if headerSize == bmpCoreHeaderSize {
+			entrySize = 3
+		}
+
+		if colors == 0 || colors > 1<<bpp {
+			colors = 1 << bpp
+		}
+
+		// Some writers store fewer entries than declared, the palette ends at the pixel data
+		if n := (dataOffset - pos) / entrySize; n < colors && n >= 0 {
+			colors = n
+		}
+
+		palette = make([][3]byte, colors)
+		for i := range palette {
+			if pos+entrySize > len(buf) {
+				return 0, 0, nil, fmt.Errorf("%w: truncated bitmap palette", ErrCorruptImage)
+			}
+
+			palette[i] = [3]byte{buf[pos+2], buf[pos+1], buf[pos]}
+			pos += entrySize
+		}
+	}
+
+	data := buf[dataOffset:]
+	rgba := make([]byte, w*h*4)
+
+	if compression == bmpRLE8 || compression == bmpRLE4 {
+		if err := decodeBMPRLE(data, w, h, compression == bmpRLE4, palette, rgba); err != nil {
+			return 0, 0, nil, err
+		}
+
+		return w, h, rgba, nil
+	}
+
+	stride := (w*bpp + 31) / 32 * 4
+	if len(data) < stride*h {
+		return 0, 0, nil, fmt.Errorf("%w: truncated bitmap data", ErrCorruptImage)
+	}
+
+	for y := 0; y < h; y++ {
+		row := data[(h-1-y)*stride:]
+		if topDown {
+			row = data[y*stride:]
+		}
+
+		for x := 0; x < w; x++ {
+			p := rgba[(y*w+x)*4:]
+
+			switch bpp {
+			case 1, 4, 8:
+				idx := int(row[x*bpp/8]>>(8-bpp-x*bpp%8)) & (1<<bpp - 1)
+				if idx < len(palette) {
+					p[0], p[1], p[2] = palette[idx][0], palette[idx][1], palette[idx][2]
+				}
+				p[3] = 255
+			case 24:
+				p[0], p[1], p[2], p[3] = row[x*3+2], row[x*3+1], row[x*3], 255
+			case 16, 32:
+				var px uint32
+				if bpp == 16 {
+					px = uint32(binary.LittleEndian.Uint16(row[x*2:]))
+				} else {
+					px = binary.LittleEndian.Uint32(row[x*4:])
+				}
+
+				p[0], p[1], p[2], p[3] = r.value(px), g.value(px), b.value(px), 255
+				if a.mask != 0 {
+					p[3] = a.value(px)
+				}
+			}
+		}
+	}
+
+	return w, h, rgba, nil
+}
+
+// decodeBMPRLE decodes run-length encoded indices, the pixels skipped by the delta escape are transparent
+func decodeBMPRLE(data []byte, w, h int, rle4 bool, palette [][3]byte, rgba []byte) error {
+	x, y := 0, 0
+
+	put := func(idx int) {
+		if x < w && y < h && idx < len(palette) {
+			// Rows are stored bottom-up
+			p := rgba[((h-1-y)*w+x)*4:]
+			p[0], p[1], p[2], p[3] = palette[idx][0], palette[idx][1], palette[idx][2], 255
+		}
+		x++
+	}
+
+	for i := 0; i+1 < len(data); {
+		count, val := int(data[i]), data[i+1]
+		i += 2
+
+		if count > 0 {
+			for n := 0; n < count; n++ {
+				if rle4 {
+					put(int(val>>(4-n%2*4)) & 0x0f)
+				} else {
+					put(int(val))
+				}
+			}
+
+			continue
+		}
+
+		switch val {
+		case 0: // end of line
+			x, y = 0, y+1
+		case 1: // end of bitmap
+			return nil
+		case 2: // delta
+			if i+1 >= len(data) {
+				return fmt.Errorf("%w: truncated RLE delta", ErrCorruptImage)
+			}
+
+			x, y = x+int(data[i]), y+int(data[i+1])
+			i += 2
+		default: // absolute run, padded to 16 bits
+			n := int(val)
+
+			size := n
+			if rle4 {
+				size = (n + 1) / 2
+			}
+
+			if i+size > len(data) {
+				return fmt.Errorf("%w: truncated RLE run", ErrCorruptImage)
+			}
+
+			for k := 0; k < n; k++ {
+				if rle4 {
+					put(int(data[i+k/2]>>(4-k%2*4)) & 0x0f)
+				} else {
+					put(int(data[i+k]))
+				}
+			}
+
+			i += size + size%2
+		}
+
+		if y >= h {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// encodeBMP writes 24-bit bitmap for opaque images and 32-bit one with the alpha mask otherwise
+func encodeBMP(w, h int, rgba []byte) []byte {
+	opaque := true
+	for i := 3; i < len(rgba); i += 4 {
+		if rgba[i] != 255 {
+			opaque = false
+			break
+		}
+	}
+
+	headerSize, bpp := dibHeaderSize, 24
+	if !opaque {
+		headerSize, bpp = bmpV4HeaderSize, 32
+	}
+
+	stride := (w*bpp + 31) / 32 * 4
+	dataOffset := bmpFileHeaderSize + headerSize
+
+	buf := make([]byte, dataOffset+stride*h)
+
+	buf[0], buf[1] = 'B', 'M'
+	binary.LittleEndian.PutUint32(buf[2:], uint32(len(buf)))
+	binary.LittleEndian.PutUint32(buf[10:], uint32(dataOffset))
+
+	dib := buf[bmpFileHeaderSize:]
+	binary.LittleEndian.PutUint32(dib[0:], uint32(headerSize))
+	binary.LittleEndian.PutUint32(dib[4:], uint32(w))
+	binary.LittleEndian.PutUint32(dib[8:], uint32(h))
+	binary.LittleEndian.PutUint16(dib[12:], 1)
+	binary.LittleEndian.PutUint16(dib[14:], uint16(bpp))
+	binary.LittleEndian.PutUint32(dib[20:], uint32(stride*h))
+
+	if !opaque {
+		binary.LittleEndian.PutUint32(dib[16:], bmpBitfields)
+		binary.LittleEndian.PutUint32(dib[40:], 0x00ff0000)
+		binary.LittleEndian.PutUint32(dib[44:], 0x0000ff00)
+		binary.LittleEndian.PutUint32(dib[48:], 0x000000ff)
+		binary.LittleEndian.PutUint32(dib[52:], 0xff000000)
+		// LCS_sRGB colour space
+		copy(dib[56:], "BGRs")
+	}
+
+	for y := 0; y < h; y++ {
+		row := buf[dataOffset+(h-1-y)*stride:]
+
+		for x := 0; x < w; x++ {
+			p := rgba[(y*w+x)*4:]
+
+			if opaque {
+				row[x*3], row[x*3+1], row[x*3+2] = p[2], p[1], p[0]
+			} else {
+				row[x*4], row[x*4+1], row[x*4+2], row[x*4+3] = p[2], p[1], p[0], p[3]
+			}
+		}
+	}
+
+	return buf
+}
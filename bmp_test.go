@@ -0,0 +1,163 @@
+package libvips_go
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+)
+
+// testBMP builds a bitmap with BITMAPINFOHEADER, the palette and the pixel data
+func testBMP(w, h, bpp int, compression uint32, palette [][3]byte, data []byte) []byte {
+	offset := bmpFileHeaderSize + dibHeaderSize + len(palette)*4
+
+	buf := make([]byte, offset, offset+len(data))
+	buf[0], buf[1] = 'B', 'M'
+	binary.LittleEndian.PutUint32(buf[10:], uint32(offset))
+
+	dib := buf[bmpFileHeaderSize:]
+	binary.LittleEndian.PutUint32(dib[0:], dibHeaderSize)
+	binary.LittleEndian.PutUint32(dib[4:], uint32(w))
+	binary.LittleEndian.PutUint32(dib[8:], uint32(h))
+	binary.LittleEndian.PutUint16(dib[12:], 1)
+	binary.LittleEndian.PutUint16(dib[14:], uint16(bpp))
+	binary.LittleEndian.PutUint32(dib[16:], compression)
+	binary.LittleEndian.PutUint32(dib[32:], uint32(len(palette)))
+
+	for i, c := range palette {
+		copy(buf[bmpFileHeaderSize+dibHeaderSize+i*4:], []byte{c[2], c[1], c[0], 0})
+	}
+
+	return append(buf, data...)
+}
+
+func TestDecodeBMP(t *testing.T) {
+	blank, err := os.ReadFile(".test/blank.bmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bw := [][3]byte{{0, 0, 0}, {255, 255, 255}}
+	rgb := [][3]byte{{255, 0, 0}, {0, 255, 0}, {0, 0, 255}}
+
+	opaque := []byte{
+		255, 0, 0, 255, 0, 255, 0, 255, 0, 0, 255, 255,
+		10, 20, 30, 255, 40, 50, 60, 255, 70, 80, 90, 255,
+	}
+	translucent := append([]byte{}, opaque...)
+	translucent[7], translucent[19] = 0, 128
+
+	topDown := testBMP(1, 2, 24, bmpRGB, nil, []byte{0, 0, 255, 0, 255, 0, 0, 0})
+	binary.LittleEndian.PutUint32(topDown[bmpFileHeaderSize+8:], uint32(0xffffffff)-1) // -2
+
+	tests := []struct {
+		name     string
+		buf      []byte
+		wantW    int
+		wantH    int
+		wantRGBA []byte
+		wantErr  bool
+	}{
+		{"Blank32Bitfields", blank, 1, 1, []byte{255, 255, 255, 255}, false},
+		{"RoundTrip24", encodeBMP(3, 2, opaque), 3, 2, opaque, false},
+		{"RoundTrip32", encodeBMP(3, 2, translucent), 3, 2, translucent, false},
+		// Bottom row first: white, black; then black, white
+		{"Paletted1", testBMP(2, 2, 1, bmpRGB, bw, []byte{0x80, 0, 0, 0, 0x40, 0, 0, 0}), 2, 2, []byte{
+			0, 0, 0, 255, 255, 255, 255, 255,
+			255, 255, 255, 255, 0, 0, 0, 255,
+		}, false},
+		{"Paletted4", testBMP(3, 1, 4, bmpRGB, rgb, []byte{0x21, 0x00, 0, 0}), 3, 1, []byte{
+			0, 0, 255, 255, 0, 255, 0, 255, 255, 0, 0, 255,
+		}, false},
+		{"Paletted8", testBMP(2, 1, 8, bmpRGB, rgb, []byte{2, 1, 0, 0}), 2, 1, []byte{
+			0, 0, 255, 255, 0, 255, 0, 255,
+		}, false},
+		{"RGB555", testBMP(1, 1, 16, bmpRGB, nil, []byte{0x1f, 0x7c, 0, 0}), 1, 1, []byte{255, 0, 255, 255}, false},
+		{"TopDown", topDown, 1, 2, []byte{255, 0, 0, 255, 0, 0, 255, 255}, false},
+		// Run of 3 red, end of line, padded absolute run of 3 (green, blue, green), end of bitmap
+		{"RLE8", testBMP(3, 2, 8, bmpRLE8, rgb, []byte{3, 0, 0, 0, 0, 3, 1, 2, 1, 0, 0, 1}), 3, 2, []byte{
+			0, 255, 0, 255, 0, 0, 255, 255, 0, 255, 0, 255,
+			255, 0, 0, 255, 255, 0, 0, 255, 255, 0, 0, 255,
+		}, false},
+		// Run of 3 alternating green and blue, delta to skip the second row, end of bitmap
+		{"RLE4", testBMP(3, 2, 4, bmpRLE4, rgb, []byte{3, 0x12, 0, 2, 0, 1, 0, 1}), 3, 2, []byte{
+			0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+			0, 255, 0, 255, 0, 0, 255, 255, 0, 255, 0, 255,
+		}, false},
+		{"Truncated", encodeBMP(3, 2, opaque)[:60], 0, 0, nil, true},
+		{"InvalidMagic", []byte("BX0000000000000000000000000000"), 0, 0, nil, true},
+		{"TooLarge", testBMP(1<<14, 1<<14, 8, bmpRLE8, rgb, []byte{0, 1}), 0, 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The header and the pixels are decoded like loadBMP does
+			hdr, err := parseBMPHeader(tt.buf)
+
+			var w, h int
+			var got []byte
+			if err == nil {
+				w, h, got, err = decodeBMP(tt.buf, hdr)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeBMP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrCorruptImage) {
+				t.Errorf("decodeBMP() error = %v, want ErrCorruptImage", err)
+			}
+			if w != tt.wantW || h != tt.wantH || !bytes.Equal(got, tt.wantRGBA) {
+				t.Errorf("decodeBMP() got = %dx%d %v, want %dx%d %v", w, h, got, tt.wantW, tt.wantH, tt.wantRGBA)
+			}
+		})
+	}
+}
+
+func TestEncodeBMP_Format(t *testing.T) {
+	got := encodeBMP(1, 1, []byte{1, 2, 3, 255})
+
+	if f := FormatByMagicNumber(got); f != BMP {
+		t.Errorf("FormatByMagicNumber() = %v, want BMP", f)
+	}
+
+	if bpp := binary.LittleEndian.Uint16(got[bmpFileHeaderSize+14:]); bpp != 24 {
+		t.Errorf("encodeBMP() depth = %d, want 24 for opaque image", bpp)
+	}
+}
+
+func TestParseBMPHeader(t *testing.T) {
+	// The header is read without the pixel data, a compressed bitmap may claim any size
+	buf := testBMP(8000, -6000, 24, bmpRGB, nil, nil)
+
+	hdr, err := parseBMPHeader(buf)
+	if err != nil {
+		t.Fatalf("parseBMPHeader() error = %v", err)
+	}
+	if hdr.w != 8000 || hdr.h != 6000 || !hdr.topDown || hdr.bpp != 24 {
+		t.Errorf("parseBMPHeader() got = %+v, want 8000x6000 top-down 24-bit", hdr)
+	}
+
+	if _, err = parseBMPHeader(buf[:bmpFileHeaderSize+4]); !errors.Is(err, ErrCorruptImage) {
+		t.Errorf("parseBMPHeader() error = %v, want ErrCorruptImage", err)
+	}
+}
+
+func TestLoadBMP_Limits(t *testing.T) {
+	// RLE end of bitmap, 8000x8000 pixels from a few bytes
+	bomb := testBMP(8000, 8000, 8, bmpRLE8, [][3]byte{{0, 0, 0}}, []byte{0, 1})
+
+	tests := []struct {
+		name string
+		opts LoadOptions
+	}{
+		{"MaxPixels", LoadOptions{MaxPixels: 1 << 20}},
+		{"MaxWidth", LoadOptions{MaxWidth: 4096}},
+		{"MaxHeight", LoadOptions{MaxHeight: 4096}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadBMP(bomb, tt.opts); !errors.Is(err, ErrImageTooLarge) {
+				t.Errorf("loadBMP() error = %v, want ErrImageTooLarge", err)
+			}
+		})
+	}
+}
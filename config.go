@@ -0,0 +1,164 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+type Config struct {
+	// CacheMax is the maximum number of operations in the libvips cache, 0 disables the cache
+	CacheMax int
+	// CacheMaxMem is the maximum amount of memory the libvips cache can use
+	CacheMaxMem uint64
+	// CacheMaxFiles is the maximum number of files the libvips cache can keep open
+	CacheMaxFiles int
+	CacheTrace    bool
+	// Concurrency is the number of worker threads of every pipeline, 0 leaves the libvips default
+	Concurrency   int
+	VectorEnabled bool
+	LeakCheck     bool
+	// BlockedOperations lists operations or whole classes, like "VipsForeignLoadMagick", which can't be
+	// used after startup. Requires libvips 8.13+
+	BlockedOperations []string
+}
+
+// DefaultConfig disables libvips cache. Since processing pipeline is fine tuned, we won't get much profit from it.
+// Enabled cache can cause SIGSEGV on Musl-based systems like Alpine.
+// Vector calculations are disabled too, they cause SIGSEGV sometimes when working with JPEG
+// and the profit is quite small.
+var DefaultConfig = Config{
+	CacheMaxFiles: 100,
+	Concurrency:   1,
+}
+
+// ConfigFromEnv returns DefaultConfig overridden by the VIPS_CACHE_MAX, VIPS_CACHE_MAX_MEM,
+// VIPS_CACHE_MAX_FILES, VIPS_CACHE_TRACE, VIPS_CONCURRENCY, VIPS_VECTOR_ENABLED, VIPS_LEAK_CHECK
+// and VIPS_BLOCKED_OPERATIONS (comma separated) environment variables
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig
+
+	var err error
+
+	if val := os.Getenv("VIPS_CACHE_MAX"); val != "" {
+		if cfg.CacheMax, err = strconv.Atoi(val); err != nil {
+			return cfg, fmt.Errorf("invalid VIPS_CACHE_MAX value %q: %s", val, err)
+		}
+	}
+
+	if val := os.Getenv("VIPS_CACHE_MAX_MEM"); val != "" {
+		if cfg.CacheMaxMem, err = strconv.ParseUint(val, 10, 64); err != nil {
+			return cfg, fmt.Errorf("invalid VIPS_CACHE_MAX_MEM value %q: %s", val, err)
+		}
+	}
+
+	if val := os.Getenv("VIPS_CACHE_MAX_FILES"); val != "" {
+		if cfg.CacheMaxFiles, err = strconv.Atoi(val); err != nil {
+			return cfg, fmt.Errorf("invalid VIPS_CACHE_MAX_FILES value %q: %s", val, err)
+		}
+	}
+
+	cfg.CacheTrace = len(os.Getenv("VIPS_CACHE_TRACE")) > 0
+
+	if val := os.Getenv("VIPS_CONCURRENCY"); val != "" {
+		if cfg.Concurrency, err = strconv.Atoi(val); err != nil {
+			return cfg, fmt.Errorf("invalid VIPS_CONCURRENCY value %q: %s", val, err)
+		}
+	}
+
+	if val := os.Getenv("VIPS_VECTOR_ENABLED"); val != "" {
+		if cfg.VectorEnabled, err = strconv.ParseBool(val); err != nil {
+			return cfg, fmt.Errorf("invalid VIPS_VECTOR_ENABLED value %q: %s", val, err)
+		}
+	}
+
+	if val := os.Getenv("VIPS_LEAK_CHECK"); val != "" {
+		if cfg.LeakCheck, err = strconv.ParseBool(val); err != nil {
+			return cfg, fmt.Errorf("invalid VIPS_LEAK_CHECK value %q: %s", val, err)
+		}
+	}
+
+	if val := os.Getenv("VIPS_BLOCKED_OPERATIONS"); val != "" {
+		for _, name := range strings.Split(val, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				cfg.BlockedOperations = append(cfg.BlockedOperations, name)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+func (cfg Config) validate() error {
+	if cfg.CacheMax < 0 {
+		return fmt.Errorf("invalid cache max value %d", cfg.CacheMax)
+	}
+
+	if cfg.CacheMaxFiles < 0 {
+		return fmt.Errorf("invalid cache max files value %d", cfg.CacheMaxFiles)
+	}
+
+	if cfg.Concurrency < 0 {
+		return fmt.Errorf("invalid concurrency value %d", cfg.Concurrency)
+	}
+
+	return nil
+}
+
+// apply expects a validated config
+func (cfg Config) apply() error {
+	C.vips_cache_set_max(C.int(cfg.CacheMax))
+	C.vips_cache_set_max_mem(C.size_t(cfg.CacheMaxMem))
+	C.vips_cache_set_max_files(C.int(cfg.CacheMaxFiles))
+	C.vips_cache_set_trace(gbool(cfg.CacheTrace))
+
+	C.vips_concurrency_set(C.int(cfg.Concurrency))
+
+	C.vips_vector_set_enabled(gbool(cfg.VectorEnabled))
+	C.vips_leak_set(gbool(cfg.LeakCheck))
+
+	for _, name := range cfg.BlockedOperations {
+		cName := C.CString(name)
+		res := C.vips_operation_block_go(cName)
+		C.free(unsafe.Pointer(cName))
+
+		if res != 0 {
+			return vipsError("Startup")
+		}
+	}
+
+	return nil
+}
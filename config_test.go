@@ -0,0 +1,96 @@
+package libvips_go
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    Config
+		wantErr bool
+	}{
+		{"Default", map[string]string{}, DefaultConfig, false},
+		{
+			"AllValues",
+			map[string]string{
+				"VIPS_CACHE_MAX":          "10",
+				"VIPS_CACHE_MAX_MEM":      "1048576",
+				"VIPS_CACHE_MAX_FILES":    "5",
+				"VIPS_CACHE_TRACE":        "1",
+				"VIPS_CONCURRENCY":        "4",
+				"VIPS_VECTOR_ENABLED":     "true",
+				"VIPS_LEAK_CHECK":         "true",
+				"VIPS_BLOCKED_OPERATIONS": "VipsForeignLoadMagick, VipsForeignSaveMagick,",
+			},
+			Config{
+				CacheMax:          10,
+				CacheMaxMem:       1048576,
+				CacheMaxFiles:     5,
+				CacheTrace:        true,
+				Concurrency:       4,
+				VectorEnabled:     true,
+				LeakCheck:         true,
+				BlockedOperations: []string{"VipsForeignLoadMagick", "VipsForeignSaveMagick"},
+			},
+			false,
+		},
+		{"LeakCheckIndependentOfVector", map[string]string{"VIPS_LEAK_CHECK": "true"},
+			Config{CacheMaxFiles: 100, Concurrency: 1, LeakCheck: true}, false},
+		{"InvalidCacheMax", map[string]string{"VIPS_CACHE_MAX": "many"}, Config{}, true},
+		{"InvalidCacheMaxMem", map[string]string{"VIPS_CACHE_MAX_MEM": "-1"}, Config{}, true},
+		{"InvalidConcurrency", map[string]string{"VIPS_CONCURRENCY": "1.5"}, Config{}, true},
+		{"InvalidVectorEnabled", map[string]string{"VIPS_VECTOR_ENABLED": "maybe"}, Config{}, true},
+		{"InvalidLeakCheck", map[string]string{"VIPS_LEAK_CHECK": "maybe"}, Config{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"VIPS_CACHE_MAX", "VIPS_CACHE_MAX_MEM", "VIPS_CACHE_MAX_FILES",
+				"VIPS_CACHE_TRACE", "VIPS_CONCURRENCY", "VIPS_VECTOR_ENABLED", "VIPS_LEAK_CHECK",
+				"VIPS_BLOCKED_OPERATIONS"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			got, err := ConfigFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ConfigFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConfigFromEnv() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"Default", DefaultConfig, false},
+		{"NegativeCacheMax", Config{CacheMax: -1}, true},
+		{"NegativeCacheMaxFiles", Config{CacheMaxFiles: -1}, true},
+		{"NegativeConcurrency", Config{Concurrency: -1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStartup_InvalidConfig(t *testing.T) {
+	if err := Startup(Config{Concurrency: -1}); err == nil || errors.Is(err, ErrAlreadyStarted) {
+		t.Fatalf("Startup() error = %v, want invalid config", err)
+	}
+
+	// libvips isn't touched by the invalid config and still starts
+	startVips(t)
+}
@@ -0,0 +1,312 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// EncodeOptions makes the encoder options usable in the caller's config structs. They are the options
+// shared by all formats, which Save and SaveTo take. Saver maps them onto the per-format options of SaveWith.
+type EncodeOptions = encodeConfig
+
+type encodeConfigJSON struct {
+	Compression     *int                 `json:"compression"`
+	HEIFCompression *HEIFCompressionType `json:"heif_compression"`
+	Interlace       *bool                `json:"interlace"`
+	Lossless        *bool                `json:"lossless"`
+	Palette         *bool                `json:"palette"`
+	Quality         *int                 `json:"quality"`
+	Strip           *bool                `json:"strip"`
+}
+
+func (hc HEIFCompressionType) MarshalText() ([]byte, error) {
+	switch hc {
+	case HEIF_COMPRESSION_HEVC:
+		return []byte("hevc"), nil
+	case HEIF_COMPRESSION_AVC:
+		return []byte("avc"), nil
+	case HEIF_COMPRESSION_JPEG:
+		return []byte("jpeg"), nil
+	case HEIF_COMPRESSION_AV1:
+		return []byte("av1"), nil
+	}
+
+	return nil, fmt.Errorf("not a valid heif compression %d", hc)
+}
+
+func (hc *HEIFCompressionType) UnmarshalText(val []byte) error {
+	txt := string(val)
+
+	switch strings.ToLower(txt) {
+	case "hevc", "h265":
+		*hc = HEIF_COMPRESSION_HEVC
+	case "avc", "h264":
+		*hc = HEIF_COMPRESSION_AVC
+	case "jpeg", "jpg":
+		*hc = HEIF_COMPRESSION_JPEG
+	case "av1":
+		*hc = HEIF_COMPRESSION_AV1
+	default:
+		return fmt.Errorf("not a valid heif compression %q", txt)
+	}
+
+	return nil
+}
+
+// ParseEncodeOptions returns DefaultEncodeConfig overridden by the q (or quality), compression,
+// heif_compression, interlace, lossless, palette and strip query parameters. Other parameters are ignored.
+func ParseEncodeOptions(values url.Values) (encodeConfig, error) {
+	ec := DefaultEncodeConfig
+
+	if err := ec.setValues(values); err != nil {
+		return DefaultEncodeConfig, err
+	}
+
+	return ec, nil
+}
+
+// UnmarshalText parses the query form, like "q=80&strip=1", on top of the current options
+func (ec *encodeConfig) UnmarshalText(val []byte) error {
+	values, err := url.ParseQuery(string(val))
+	if err != nil {
+		return fmt.Errorf("invalid encode options %q: %s", val, err)
+	}
+
+	for key := range values {
+		if !isEncodeOption(key) {
+			return fmt.Errorf("unknown encode option %q", key)
+		}
+	}
+
+	return ec.setValues(values)
+}
+
+// UnmarshalJSON accepts either an object with the option names as keys or a string in the query form
+func (ec *encodeConfig) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var txt string
+		if err := json.Unmarshal(data, &txt); err != nil {
+			return err
+		}
+
+		return ec.UnmarshalText([]byte(txt))
+	}
+
+	var raw encodeConfigJSON
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return fmt.Errorf("invalid encode options: %s", err)
+	}
+
+	tmp := *ec
+
+	if raw.Compression != nil {
+		if err := tmp.setCompression(*raw.Compression); err != nil {
+			return err
+		}
+	}
+
+	if raw.HEIFCompression != nil {
+		tmp.HEIFCompression(*raw.HEIFCompression)
+	}
+
+	if raw.Interlace != nil {
+		tmp.Interlace(*raw.Interlace)
+	}
+
+	if raw.Lossless != nil {
+		tmp.Lossless(*raw.Lossless)
+	}
+
+	if raw.Palette != nil {
+		tmp.Palette(*raw.Palette)
+	}
+
+	if raw.Quality != nil {
+		if err := tmp.setQuality(*raw.Quality); err != nil {
+			return err
+		}
+	}
+
+	if raw.Strip != nil {
+		tmp.StripMetadata(*raw.Strip)
+	}
+
+	*ec = tmp
+
+	return nil
+}
+
+func isEncodeOption(key string) bool {
+	switch key {
+	case "q", "quality", "compression", "heif_compression", "interlace", "lossless", "palette", "strip":
+		return true
+	}
+
+	return false
+}
+
+func (ec *encodeConfig) setValues(values url.Values) error {
+	tmp := *ec
+
+	for _, key := range []string{"q", "quality", "compression"} {
+		val := values.Get(key)
+		if val == "" {
+			continue
+		}
+
+		i, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("invalid %s value %q: %s", key, val, err)
+		}
+
+		if key == "compression" {
+			err = tmp.setCompression(i)
+		} else {
+			err = tmp.setQuality(i)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if val := values.Get("heif_compression"); val != "" {
+		var hc HEIFCompressionType
+		if err := hc.UnmarshalText([]byte(val)); err != nil {
+			return fmt.Errorf("invalid heif_compression value %q: %s", val, err)
+		}
+
+		tmp.HEIFCompression(hc)
+	}
+
+	for _, opt := range []struct {
+		key string
+		set func(bool)
+	}{
+		{"interlace", tmp.Interlace},
+		{"lossless", tmp.Lossless},
+		{"palette", tmp.Palette},
+		{"strip", tmp.StripMetadata},
+	} {
+		val := values.Get(opt.key)
+		if val == "" {
+			continue
+		}
+
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("invalid %s value %q: %s", opt.key, val, err)
+		}
+
+		opt.set(b)
+	}
+
+	*ec = tmp
+
+	return nil
+}
+
+// Saver returns the per-format options of imgType set from the shared ones, the other fields keep
+// the defaults. The shared values are kept as parsed, compression=0 saves PNG uncompressed. The result
+// may be adjusted and passed to SaveWith or SaveToWith.
+func (ec encodeConfig) Saver(imgType ImageFormat) (Saver, error) {
+	quality := int(ec.quality)
+	strip := ec.strip != 0
+	lossless := ec.lossless != 0
+	interlace := ec.interlace != 0
+
+	switch imgType {
+	case JPEG:
+		opts := DefaultJpegOptions
+		opts.Quality, opts.StripMetadata, opts.Interlace = quality, strip, interlace
+		return opts, nil
+	case PNG:
+		opts := DefaultPngOptions
+		opts.Compression, opts.StripMetadata, opts.Interlace = int(ec.compression), strip, interlace
+		opts.Palette = ec.palette != 0
+		return opts, nil
+	case WEBP:
+		opts := DefaultWebpOptions
+		opts.Quality, opts.StripMetadata, opts.Lossless = quality, strip, lossless
+		return opts, nil
+	case HEIF:
+		opts := DefaultHeifOptions
+		opts.Quality, opts.StripMetadata, opts.Lossless = quality, strip, lossless
+		opts.Compression = HEIFCompressionType(ec.heifCompression)
+		return opts, nil
+	case AVIF:
+		opts := DefaultAvifOptions
+		opts.Quality, opts.StripMetadata, opts.Lossless = quality, strip, lossless
+		return opts, nil
+	case TIFF:
+		opts := DefaultTiffOptions
+		opts.Quality, opts.StripMetadata = quality, strip
+		return opts, nil
+	case JXL:
+		return jxlOptions(ec), nil
+	case JP2K:
+		return jp2kOptions(ec), nil
+	case GIF:
+		return DefaultGifOptions, nil
+	}
+
+	return nil, ErrUnsupportedImageFormat
+}
+
+func (ec *encodeConfig) setQuality(i int) error {
+	if i < 1 || i > 100 {
+		return fmt.Errorf("invalid quality value %d: must be in range 1-100", i)
+	}
+
+	ec.Quality(i)
+
+	return nil
+}
+
+func (ec *encodeConfig) setCompression(i int) error {
+	if i < 0 || i > 9 {
+		return fmt.Errorf("invalid compression value %d: must be in range 0-9", i)
+	}
+
+	ec.Compression(i)
+
+	return nil
+}
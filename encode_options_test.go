@@ -0,0 +1,201 @@
+package libvips_go
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func encodeOptions(set func(ec *encodeConfig)) encodeConfig {
+	ec := DefaultEncodeConfig
+	set(&ec)
+
+	return ec
+}
+
+func TestParseEncodeOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    encodeConfig
+		wantErr string
+	}{
+		{"Empty", "", DefaultEncodeConfig, ""},
+		{"Quality", "q=80", encodeOptions(func(ec *encodeConfig) { ec.Quality(80) }), ""},
+		{"QualityLongName", "quality=60", encodeOptions(func(ec *encodeConfig) { ec.Quality(60) }), ""},
+		{"Bools", "strip=1&lossless=0&interlace=true&palette=false", encodeOptions(func(ec *encodeConfig) {
+			ec.StripMetadata(true)
+			ec.Lossless(false)
+			ec.Interlace(true)
+			ec.Palette(false)
+		}), ""},
+		{"Compression", "compression=9&heif_compression=av1", encodeOptions(func(ec *encodeConfig) {
+			ec.Compression(9)
+			ec.HEIFCompression(HEIF_COMPRESSION_AV1)
+		}), ""},
+		{"NoCompression", "compression=0", encodeOptions(func(ec *encodeConfig) { ec.Compression(0) }), ""},
+		{"OtherParamsIgnored", "w=100&q=70", encodeOptions(func(ec *encodeConfig) { ec.Quality(70) }), ""},
+		{"QualityNotANumber", "q=high", DefaultEncodeConfig, "q"},
+		{"QualityOutOfRange", "q=101", DefaultEncodeConfig, "quality"},
+		{"CompressionOutOfRange", "compression=10", DefaultEncodeConfig, "compression"},
+		{"InvalidBool", "strip=maybe", DefaultEncodeConfig, "strip"},
+		{"InvalidHeifCompression", "heif_compression=mp3", DefaultEncodeConfig, "heif_compression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ParseEncodeOptions(values)
+			if (err != nil) != (tt.wantErr != "") {
+				t.Fatalf("ParseEncodeOptions() error = %v, wantErr %q", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseEncodeOptions() error = %v, want it to name %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseEncodeOptions() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeConfig_UnmarshalText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    encodeConfig
+		wantErr bool
+	}{
+		{"Query", "q=80&strip=1", encodeOptions(func(ec *encodeConfig) {
+			ec.Quality(80)
+			ec.StripMetadata(true)
+		}), false},
+		{"UnknownOption", "q=80&qualty=70", DefaultEncodeConfig, true},
+		{"InvalidValue", "lossless=2", DefaultEncodeConfig, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DefaultEncodeConfig
+
+			err := got.UnmarshalText([]byte(tt.text))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalText() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnmarshalText() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeConfig_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    encodeConfig
+		wantErr string
+	}{
+		{"Object", `{"quality": 80, "strip": true, "heif_compression": "avc"}`, encodeOptions(func(ec *encodeConfig) {
+			ec.Quality(80)
+			ec.StripMetadata(true)
+			ec.HEIFCompression(HEIF_COMPRESSION_AVC)
+		}), ""},
+		{"QueryString", `"q=50&lossless=0"`, encodeOptions(func(ec *encodeConfig) {
+			ec.Quality(50)
+			ec.Lossless(false)
+		}), ""},
+		{"UnknownField", `{"qualty": 80}`, DefaultEncodeConfig, "qualty"},
+		{"QualityOutOfRange", `{"quality": 0}`, DefaultEncodeConfig, "quality"},
+		{"WrongType", `{"strip": "yes"}`, DefaultEncodeConfig, "strip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var preset struct {
+				Encode EncodeOptions `json:"encode"`
+			}
+			preset.Encode = DefaultEncodeConfig
+
+			err := json.Unmarshal([]byte(`{"encode": `+tt.data+`}`), &preset)
+			if (err != nil) != (tt.wantErr != "") {
+				t.Fatalf("UnmarshalJSON() error = %v, wantErr %q", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("UnmarshalJSON() error = %v, want it to name %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(preset.Encode, tt.want) {
+				t.Errorf("UnmarshalJSON() got = %v, want %v", preset.Encode, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeConfig_Saver(t *testing.T) {
+	ec := encodeOptions(func(ec *encodeConfig) {
+		ec.Quality(80)
+		ec.Compression(9)
+		ec.StripMetadata(true)
+		ec.Interlace(false)
+		ec.Lossless(false)
+		ec.Palette(false)
+		ec.HEIFCompression(HEIF_COMPRESSION_AV1)
+	})
+
+	tests := []struct {
+		name    string
+		imgType ImageFormat
+		want    Saver
+		wantErr bool
+	}{
+		{"JPEG", JPEG, JpegOptions{Quality: 80, StripMetadata: true, Subsample: SubsampleAuto}, false},
+		{"PNG", PNG, PngOptions{Compression: 9, StripMetadata: true, Filter: PngFilterNone, Quality: 100, Dither: 1.0,
+			Effort: 7}, false},
+		{"WEBP", WEBP, WebpOptions{Quality: 80, StripMetadata: true, AlphaQuality: 100, Effort: 4}, false},
+		{"HEIF", HEIF, HeifOptions{Quality: 80, StripMetadata: true, Compression: HEIF_COMPRESSION_AV1, Effort: 4},
+			false},
+		{"AVIF", AVIF, AvifOptions{Quality: 80, StripMetadata: true, Effort: 4}, false},
+		{"TIFF", TIFF, TiffOptions{Quality: 80, StripMetadata: true, Predictor: TiffPredictorHorizontal,
+			TileWidth: 128, TileHeight: 128}, false},
+		{"JXL", JXL, JxlOptions{Distance: jxlDistance(80), Effort: 7, StripMetadata: true}, false},
+		{"JP2K", JP2K, Jp2kOptions{TileWidth: 512, TileHeight: 512, Quality: 80, StripMetadata: true}, false},
+		{"GIF", GIF, DefaultGifOptions, false},
+		{"BMP", BMP, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ec.Saver(tt.imgType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Saver() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Saver() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeConfig_Saver_NoCompression(t *testing.T) {
+	values, err := url.ParseQuery("compression=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ec, err := ParseEncodeOptions(values)
+	if err != nil {
+		t.Fatalf("ParseEncodeOptions() error = %v", err)
+	}
+
+	saver, err := ec.Saver(PNG)
+	if err != nil {
+		t.Fatalf("Saver() error = %v", err)
+	}
+
+	// The compression sent to libvips is the one asked for
+	if got := saver.(PngOptions).withDefaults().Compression; got != 0 {
+		t.Errorf("Saver() compression = %d, want 0", got)
+	}
+}
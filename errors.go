@@ -0,0 +1,142 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrCorruptImage      = fmt.Errorf("corrupt image")
+	ErrLoaderUnavailable = fmt.Errorf("image loader is unavailable")
+	ErrSaverUnavailable  = fmt.Errorf("image saver is unavailable")
+	ErrOutOfMemory       = fmt.Errorf("out of memory")
+)
+
+// VipsError is a failure reported by libvips. It matches one of ErrCorruptImage, ErrLoaderUnavailable,
+// ErrSaverUnavailable or ErrOutOfMemory with errors.Is when the cause is recognized.
+type VipsError struct {
+	// Op is the name of the library function which failed, e.g. "Save"
+	Op string
+	// Domain is the libvips domain of the first message, e.g. "jpegload_buffer"
+	Domain string
+	// Messages are all lines of the libvips error buffer
+	Messages []string
+
+	kind error
+}
+
+func (e *VipsError) Error() string {
+	msg := strings.Join(e.Messages, "; ")
+	if msg == "" {
+		msg = "unknown libvips error"
+	}
+
+	if e.Op == "" {
+		return msg
+	}
+
+	return e.Op + ": " + msg
+}
+
+func (e *VipsError) Unwrap() error {
+	return e.kind
+}
+
+// vipsError takes the content of the libvips error buffer and clears it
+func vipsError(op string) error {
+	defer C.vips_error_clear()
+	return newVipsError(op, C.GoString(C.vips_error_buffer()))
+}
+
+func newVipsError(op, buf string) *VipsError {
+	e := &VipsError{Op: op}
+
+	for _, line := range strings.Split(buf, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			e.Messages = append(e.Messages, line)
+		}
+	}
+
+	if len(e.Messages) > 0 {
+		if i := strings.Index(e.Messages[0], ": "); i > 0 {
+			e.Domain = e.Messages[0][:i]
+		}
+	}
+
+	e.kind = classifyVipsError(e.Messages)
+
+	return e
+}
+
+func classifyVipsError(messages []string) error {
+	var corrupt bool
+
+	for _, msg := range messages {
+		domain, text := "", strings.ToLower(msg)
+		if i := strings.Index(msg, ": "); i > 0 {
+			domain, text = strings.ToLower(msg[:i]), strings.ToLower(msg[i+2:])
+		}
+
+		switch {
+		case strings.Contains(text, "out of memory") || strings.Contains(text, "unable to allocate") ||
+			strings.Contains(text, "memory allocation failed"):
+			return ErrOutOfMemory
+		// Operations of a missing module or blocked ones, e.g. VipsOperation: class "magickload_buffer" not found
+		case (strings.Contains(text, "class") && strings.Contains(text, "not found")) ||
+			strings.Contains(text, "is blocked"):
+			if strings.Contains(text, "save") {
+				return ErrSaverUnavailable
+			}
+
+			if strings.Contains(text, "load") {
+				return ErrLoaderUnavailable
+			}
+		case strings.Contains(text, "is not a known file format") || strings.Contains(text, "unsupported image format"):
+			if strings.Contains(domain, "save") {
+				return ErrSaverUnavailable
+			}
+
+			return ErrLoaderUnavailable
+		case strings.Contains(domain, "load") || strings.Contains(text, "premature end") ||
+			strings.Contains(text, "corrupt") || strings.Contains(text, "truncated"):
+			corrupt = true
+		}
+	}
+
+	if corrupt {
+		return ErrCorruptImage
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package libvips_go
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNewVipsError(t *testing.T) {
+	tests := []struct {
+		name       string
+		op         string
+		buf        string
+		wantDomain string
+		wantMsgs   []string
+		wantKind   error
+		wantString string
+	}{
+		{
+			"CorruptJPEG", "Load",
+			"VipsJpeg: Premature end of JPEG file\njpegload_buffer: out of order read at line 10\n",
+			"VipsJpeg",
+			[]string{"VipsJpeg: Premature end of JPEG file", "jpegload_buffer: out of order read at line 10"},
+			ErrCorruptImage,
+			"Load: VipsJpeg: Premature end of JPEG file; jpegload_buffer: out of order read at line 10",
+		},
+		{
+			"LoaderFailure", "Load",
+			"pngload_buffer: libspng read error\n",
+			"pngload_buffer", []string{"pngload_buffer: libspng read error"}, ErrCorruptImage,
+			"Load: pngload_buffer: libspng read error",
+		},
+		{
+			"MissingLoader", "Load",
+			"VipsOperation: class \"magickload_buffer\" not found\n",
+			"VipsOperation", []string{"VipsOperation: class \"magickload_buffer\" not found"}, ErrLoaderUnavailable,
+			"Load: VipsOperation: class \"magickload_buffer\" not found",
+		},
+		{
+			"MissingSaver", "Save",
+			"VipsOperation: class \"magicksave_buffer\" not found\n",
+			"VipsOperation", []string{"VipsOperation: class \"magicksave_buffer\" not found"}, ErrSaverUnavailable,
+			"Save: VipsOperation: class \"magicksave_buffer\" not found",
+		},
+		{
+			"UnsupportedFormat", "LoadWithOptions",
+			"vips_image_load: Unsupported image format\n",
+			"vips_image_load", []string{"vips_image_load: Unsupported image format"}, ErrLoaderUnavailable,
+			"LoadWithOptions: vips_image_load: Unsupported image format",
+		},
+		{
+			"OutOfMemory", "Resize",
+			"vips_tracked: out of memory --- size == 1024MB\n",
+			"vips_tracked", []string{"vips_tracked: out of memory --- size == 1024MB"}, ErrOutOfMemory,
+			"Resize: vips_tracked: out of memory --- size == 1024MB",
+		},
+		{
+			"Unclassified", "Crop",
+			"extract_area: bad extract area\n",
+			"extract_area", []string{"extract_area: bad extract area"}, nil,
+			"Crop: extract_area: bad extract area",
+		},
+		{
+			"FormatVerbs", "Save",
+			"jpegsave_buffer: 100% done %s\n",
+			"jpegsave_buffer", []string{"jpegsave_buffer: 100% done %s"}, nil,
+			"Save: jpegsave_buffer: 100% done %s",
+		},
+		{"EmptyBuffer", "Save", "", "", nil, nil, "Save: unknown libvips error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newVipsError(tt.op, tt.buf)
+			if e.Domain != tt.wantDomain {
+				t.Errorf("Domain = %q, want %q", e.Domain, tt.wantDomain)
+			}
+			if !reflect.DeepEqual(e.Messages, tt.wantMsgs) {
+				t.Errorf("Messages = %q, want %q", e.Messages, tt.wantMsgs)
+			}
+			if got := e.Error(); got != tt.wantString {
+				t.Errorf("Error() = %q, want %q", got, tt.wantString)
+			}
+			for _, kind := range []error{ErrCorruptImage, ErrLoaderUnavailable, ErrSaverUnavailable, ErrOutOfMemory} {
+				if got := errors.Is(e, kind); got != (kind == tt.wantKind) {
+					t.Errorf("errors.Is(%v) = %v, want %v", kind, got, kind == tt.wantKind)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"context"
+	"io"
+	"runtime/cgo"
+	"sync"
+	"time"
+)
+
+type ProgressStage int
+
+const (
+	ProgressStart   = ProgressStage(C.PREEVAL)
+	ProgressRunning = ProgressStage(C.EVAL)
+	ProgressDone    = ProgressStage(C.POSTEVAL)
+)
+
+type Progress struct {
+	Stage       ProgressStage
+	Percent     int
+	Pixels      int64
+	TotalPixels int64
+	// Run is the time elapsed since the evaluation start
+	Run time.Duration
+	ETA time.Duration
+}
+
+type evalWatcher struct {
+	ctx context.Context
+}
+
+type progressWatcher struct {
+	mu sync.Mutex
+	fn func(Progress)
+}
+
+// OnProgress calls fn when the image, or any image derived from it, starts evaluation, processes
+// some pixels and finishes. The evaluation runs in libvips worker threads, fn is never called concurrently
+// but it should return quickly. Every call adds one more callback.
+func (img *VipsImage) OnProgress(fn func(Progress)) {
+	C.vips_image_connect_progress_go(img.img, C.uintptr_t(cgo.NewHandle(&progressWatcher{fn: fn})))
+}
+
+// watchContext returns a copy of the image which evaluation, and the evaluation of every image derived
+// from the copy, is killed once ctx is done. The kill flag and the progress signal stay on the copy, so
+// the image itself may be saved again. The returned function must be called when the evaluation is over,
+// it clears the copy.
+func (img *VipsImage) watchContext(ctx context.Context) (*VipsImage, func(), error) {
+	out := &VipsImage{}
+	if C.vips_copy_go(img.img, &out.img) != 0 {
+		return nil, nil, vipsError("watchContext")
+	}
+
+	if ctx.Done() == nil {
+		return out, out.Clear, nil
+	}
+
+	handle := cgo.NewHandle(&evalWatcher{ctx: ctx})
+
+	in := out.img
+	id := C.vips_image_connect_eval_go(in, C.uintptr_t(handle))
+
+	return out, func() {
+		C.vips_image_disconnect_eval_go(in, id)
+		handle.Delete()
+		out.Clear()
+	}, nil
+}
+
+// SaveContext is Save which aborts encoding and returns ctx.Err() once ctx is done
+func (img *VipsImage) SaveContext(ctx context.Context, imgType ImageFormat, opts encodeConfig) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	watched, stop, err := img.watchContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := watched.Save(imgType, opts)
+	stop()
+
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return buf, err
+}
+
+// SaveToContext is SaveTo which aborts encoding and returns ctx.Err() once ctx is done
+func (img *VipsImage) SaveToContext(ctx context.Context, w io.Writer, imgType ImageFormat, opts encodeConfig) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	watched, stop, err := img.watchContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = watched.SaveTo(w, imgType, opts)
+	stop()
+
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// ThumbnailContext is Thumbnail which decodes and shrinks the image into memory before returning,
+// so the long part of the work can be aborted with ctx
+func ThumbnailContext(ctx context.Context, buf []byte, width, height int, opts ThumbnailOptions) (*VipsImage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	img, err := Thumbnail(buf, width, height, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer img.Clear()
+
+	watched, stop, err := img.watchContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &VipsImage{}
+	if out.img = C.vips_image_copy_memory(watched.img); out.img == nil {
+		err = vipsError("ThumbnailContext")
+	}
+	stop()
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		return nil, err
+	}
+
+	return out, nil
+}
+
+//export goImageEval
+func goImageEval(handle C.uintptr_t, progress *C.VipsProgress) C.int {
+	watcher := cgo.Handle(handle).Value().(*evalWatcher)
+
+	if watcher.ctx.Err() != nil {
+		return 1
+	}
+
+	return 0
+}
+
+//export goImageProgress
+func goImageProgress(handle C.uintptr_t, stage C.int, progress *C.VipsProgress) {
+	watcher := cgo.Handle(handle).Value().(*progressWatcher)
+
+	p := Progress{
+		Stage:       ProgressStage(stage),
+		Percent:     int(progress.percent),
+		Pixels:      int64(progress.npels),
+		TotalPixels: int64(progress.tpels),
+		Run:         time.Duration(progress.run) * time.Second,
+		ETA:         time.Duration(progress.eta) * time.Second,
+	}
+
+	if progress.start != nil {
+		p.Run = time.Duration(float64(C.g_timer_elapsed(progress.start, nil)) * float64(time.Second))
+	}
+
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+
+	watcher.fn(p)
+}
@@ -0,0 +1,150 @@
+package libvips_go
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+// expiringContext is done after its Err is checked calls times
+type expiringContext struct {
+	context.Context
+	calls int32
+}
+
+func (ctx *expiringContext) Err() error {
+	if atomic.AddInt32(&ctx.calls, -1) < 0 {
+		return context.Canceled
+	}
+
+	return nil
+}
+
+func TestVipsImage_SaveContext(t *testing.T) {
+	img := loadFixture(t, "wiki_a4.png")
+	if err := img.CopyMemory(); err != nil {
+		t.Fatal(err)
+	}
+
+	live, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{"Background", context.Background(), nil},
+		{"Live", live, nil},
+		{"Cancelled before", cancelled, context.Canceled},
+		// The first check passes, the evaluation is killed on the first eval signal
+		{"Cancelled while saving", &expiringContext{Context: live, calls: 1}, context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := img.SaveContext(tt.ctx, JPEG, DefaultEncodeConfig)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SaveContext() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err == nil && FormatByMagicNumber(buf) != JPEG {
+				t.Errorf("SaveContext() wrote %s, want JPEG", FormatByMagicNumber(buf))
+			}
+
+			// The cancelled save doesn't affect the image
+			if _, err = img.Save(JPEG, DefaultEncodeConfig); err != nil {
+				t.Errorf("Save() after SaveContext() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestVipsImage_SaveContext_Progress(t *testing.T) {
+	img := loadFixture(t, "wiki_a4.png")
+
+	var stages []ProgressStage
+	img.OnProgress(func(p Progress) { stages = append(stages, p.Stage) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := img.SaveContext(ctx, PNG, DefaultEncodeConfig); err != nil {
+		t.Fatalf("SaveContext() error = %v", err)
+	}
+
+	// The progress of the watched copy is forwarded to the image
+	if len(stages) < 2 || stages[0] != ProgressStart || stages[len(stages)-1] != ProgressDone {
+		t.Errorf("OnProgress() stages = %v, want start ... done", stages)
+	}
+}
+
+func TestThumbnailContext(t *testing.T) {
+	startVips(t)
+
+	buf := readFixture(t, "wiki_a4.png")
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ThumbnailContext(cancelled, buf, 100, 100, ThumbnailOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("ThumbnailContext() error = %v, want %v", err, context.Canceled)
+	}
+
+	expiring := &expiringContext{Context: context.Background(), calls: 1}
+	if _, err := ThumbnailContext(expiring, buf, 100, 100, ThumbnailOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("ThumbnailContext() error = %v, want %v", err, context.Canceled)
+	}
+
+	img, err := ThumbnailContext(context.Background(), buf, 150, 300, ThumbnailOptions{})
+	if err != nil {
+		t.Fatalf("ThumbnailContext() error = %v", err)
+	}
+	defer img.Clear()
+
+	if img.Width() != 150 || img.Height() != 225 {
+		t.Errorf("ThumbnailContext() size = %dx%d, want 150x225", img.Width(), img.Height())
+	}
+}
+
+func TestVipsImage_OnProgress(t *testing.T) {
+	img := loadFixture(t, "wiki_a4.png")
+
+	var first, second []Progress
+	img.OnProgress(func(p Progress) { first = append(first, p) })
+	img.OnProgress(func(p Progress) { second = append(second, p) })
+
+	if _, err := img.Save(PNG, DefaultEncodeConfig); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if len(first) < 2 {
+		t.Fatalf("OnProgress() got %d calls, want at least start and done", len(first))
+	}
+
+	if len(second) != len(first) {
+		t.Errorf("OnProgress() second callback got %d calls, want %d", len(second), len(first))
+	}
+
+	if first[0].Stage != ProgressStart {
+		t.Errorf("OnProgress() first stage = %v, want %v", first[0].Stage, ProgressStart)
+	}
+
+	last := first[len(first)-1]
+	if last.Stage != ProgressDone {
+		t.Errorf("OnProgress() last stage = %v, want %v", last.Stage, ProgressDone)
+	}
+
+	if want := int64(img.Width() * img.Height()); last.TotalPixels != want {
+		t.Errorf("OnProgress() total pixels = %d, want %d", last.TotalPixels, want)
+	}
+
+	for i, p := range first[1 : len(first)-1] {
+		if p.Stage != ProgressRunning {
+			t.Errorf("OnProgress() stage %d = %v, want %v", i+1, p.Stage, ProgressRunning)
+		}
+	}
+}
@@ -17,6 +17,9 @@ func checkErr(e error) {
 }
 
 func main() {
+	cfg, err := vips.ConfigFromEnv()
+	checkErr(err)
+	checkErr(vips.Startup(cfg))
 	defer vips.Shutdown()
 
 	if len(os.Args) < 3 {
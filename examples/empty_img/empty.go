@@ -16,6 +16,9 @@ func checkErr(e error) {
 }
 
 func main() {
+	cfg, err := vips.ConfigFromEnv()
+	checkErr(err)
+	checkErr(vips.Startup(cfg))
 	defer vips.Shutdown()
 
 	vipsImage := vips.Pixel()
@@ -16,6 +16,9 @@ func checkErr(e error) {
 }
 
 func main() {
+	cfg, err := vips.ConfigFromEnv()
+	checkErr(err)
+	checkErr(vips.Startup(cfg))
 	defer vips.Shutdown()
 
 	var arrVips []*vips.VipsImage
@@ -32,13 +35,11 @@ func main() {
 		}
 	}()
 
-	jVips, err := vips.Join(arrVips)
-	checkErr(err)
-	defer jVips.Clear()
-
-	jVips.SetInt("page-height", jVips.Height()/len(arrVips))
+	opts := vips.DefaultPDFOptions
+	opts.Margin = 36
+	opts.Title = "Wiki A4"
 
-	buf, err := jVips.Save(vips.PDF, vips.DefaultEncodeConfig)
+	buf, err := vips.SavePDF(arrVips, opts)
 	checkErr(err)
 
 	checkErr(ioutil.WriteFile("a4-2.pdf", buf, 0644))
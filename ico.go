@@ -0,0 +1,271 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"unsafe"
+)
+
+const (
+	icoTypeIcon   = 1
+	icoTypeCursor = 2
+
+	icoMaxSize      = 256
+	icoDirSize      = 6
+	icoDirEntrySize = 16
+	dibHeaderSize   = 40
+)
+
+// DefaultIcoSizes are the favicon sizes commonly requested by browsers and platforms
+var DefaultIcoSizes = []int{16, 32, 48, 64, 128, 256}
+
+type IcoOptions struct {
+	// BMPMaxSize is the largest entry size stored as an uncompressed bitmap instead of PNG.
+	// Old Windows versions can't read PNG entries, 48 covers them. 0 stores every entry as PNG.
+	BMPMaxSize int
+}
+
+type icoEntry struct {
+	width, height int
+	// planes and bpp of an icon are the hotspot coordinates of a cursor
+	planes, bpp int
+	data        []byte
+}
+
+// SaveIco writes an icon with an entry per size. Every entry is the image downscaled to fit
+// into a size x size square. The image is left decoded in memory, like after CopyMemory.
+func (img *VipsImage) SaveIco(sizes []int, opts IcoOptions) ([]byte, error) {
+	entries, err := img.icoEntries(sizes, opts, func(w, h int) (int, int) {
+		return 1, 32
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeIco(icoTypeIcon, entries)
+}
+
+// SaveCur writes a cursor like SaveIco. The hotspot is given in the image coordinates and scaled for every entry.
+func (img *VipsImage) SaveCur(sizes []int, hotspot image.Point, opts IcoOptions) ([]byte, error) {
+	srcW, srcH := img.Width(), img.Height()
+	if !hotspot.In(image.Rect(0, 0, srcW, srcH)) {
+		return nil, fmt.Errorf("hotspot %v is out of the %dx%d image", hotspot, srcW, srcH)
+	}
+
+	entries, err := img.icoEntries(sizes, opts, func(w, h int) (int, int) {
+		return hotspot.X * w / srcW, hotspot.Y * h / srcH
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeIco(icoTypeCursor, entries)
+}
+
+func (img *VipsImage) icoEntries(sizes []int, opts IcoOptions, fields func(w, h int) (int, int)) ([]icoEntry, error) {
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("no icon sizes")
+	}
+
+	seen := make(map[int]bool, len(sizes))
+	for _, size := range sizes {
+		if size < 1 || size > icoMaxSize {
+			return nil, fmt.Errorf("invalid icon size %d. Max dimension size for ICO is %d", size, icoMaxSize)
+		}
+
+		if seen[size] {
+			return nil, fmt.Errorf("duplicate icon size %d", size)
+		}
+		seen[size] = true
+	}
+
+	// Every entry reads the image again, it's decoded into memory in place and stays usable
+	if err := img.CopyMemory(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]icoEntry, 0, len(sizes))
+	for _, size := range sizes {
+		entry, err := img.icoEntry(size, size <= opts.BMPMaxSize)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.planes, entry.bpp = fields(entry.width, entry.height)
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (img *VipsImage) icoEntry(size int, bmp bool) (icoEntry, error) {
+	scaled := img.ref()
+	defer scaled.Clear()
+
+	longest := img.Width()
+	if img.Height() > longest {
+		longest = img.Height()
+	}
+
+	if longest != size {
+		if err := scaled.Resize(float64(size)/float64(longest), scaled.HasAlpha()); err != nil {
+			return icoEntry{}, err
+		}
+	}
+
+	entry := icoEntry{width: scaled.Width(), height: scaled.Height()}
+	if entry.width > icoMaxSize || entry.height > icoMaxSize {
+		return icoEntry{}, fmt.Errorf("icon entry %dx%d is too big", entry.width, entry.height)
+	}
+
+	if bmp {
+		pix, err := scaled.rgbaPixels()
+		if err != nil {
+			return icoEntry{}, err
+		}
+
+		entry.data = encodeIcoDIB(entry.width, entry.height, pix)
+
+		return entry, nil
+	}
+
+	imgSize := C.size_t(0)
+
+	var ptr unsafe.Pointer
+	defer C.g_free_go(&ptr)
+
+	if C.vips_pngsave_go(scaled.img, &ptr, &imgSize, 6, 0, 0, 0) != 0 {
+		return icoEntry{}, vipsError("SaveIco")
+	}
+
+	entry.data = C.GoBytes(ptr, C.int(imgSize))
+
+	return entry, nil
+}
+
+// rgbaPixels returns the image as 8-bit sRGB with alpha, 4 bytes per pixel
+func (img *VipsImage) rgbaPixels() ([]byte, error) {
+	size := C.size_t(0)
+
+	ptr := C.vips_rgba_pixels_go(img.img, &size)
+	if ptr == nil {
+		return nil, vipsError("rgbaPixels")
+	}
+	defer C.g_free(C.gpointer(ptr))
+
+	return C.GoBytes(unsafe.Pointer(ptr), C.int(size)), nil
+}
+
+// encodeIco writes the ICONDIR header, the ICONDIRENTRY list and the entries data
+func encodeIco(kind uint16, entries []icoEntry) ([]byte, error) {
+	if len(entries) > 0xffff {
+		return nil, fmt.Errorf("too many icon entries %d", len(entries))
+	}
+
+	dataSize := 0
+	for _, e := range entries {
+		dataSize += len(e.data)
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Grow(icoDirSize + icoDirEntrySize*len(entries) + dataSize)
+
+	// ICONDIR: reserved, type and number of entries
+	for _, v := range []uint16{0, kind, uint16(len(entries))} {
+		if err := binary.Write(buf, binary.LittleEndian, v); err != nil {
+			return nil, err
+		}
+	}
+
+	offset := icoDirSize + icoDirEntrySize*len(entries)
+	for _, e := range entries {
+		// ICONDIRENTRY. 0 means 256 pixels, the number of colors and reserved bytes are always 0.
+		if _, err := buf.Write([]byte{byte(e.width % icoMaxSize), byte(e.height % icoMaxSize), 0, 0}); err != nil {
+			return nil, err
+		}
+
+		for _, v := range []uint32{uint32(e.planes) | uint32(e.bpp)<<16, uint32(len(e.data)), uint32(offset)} {
+			if err := binary.Write(buf, binary.LittleEndian, v); err != nil {
+				return nil, err
+			}
+		}
+
+		offset += len(e.data)
+	}
+
+	for _, e := range entries {
+		if _, err := buf.Write(e.data); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeIcoDIB writes a 32-bit BMP entry without the file header: BITMAPINFOHEADER with the doubled height,
+// bottom-up BGRA rows and the AND mask, which is set for transparent pixels
+func encodeIcoDIB(w, h int, rgba []byte) []byte {
+	maskStride := (w + 31) / 32 * 4
+
+	buf := make([]byte, dibHeaderSize, dibHeaderSize+w*h*4+maskStride*h)
+
+	binary.LittleEndian.PutUint32(buf[0:], dibHeaderSize)
+	binary.LittleEndian.PutUint32(buf[4:], uint32(w))
+	binary.LittleEndian.PutUint32(buf[8:], uint32(h*2))
+	binary.LittleEndian.PutUint16(buf[12:], 1)
+	binary.LittleEndian.PutUint16(buf[14:], 32)
+	binary.LittleEndian.PutUint32(buf[20:], uint32(w*h*4+maskStride*h))
+
+	for y := h - 1; y >= 0; y-- {
+		for x := 0; x < w; x++ {
+			p := rgba[(y*w+x)*4:]
+			buf = append(buf, p[2], p[1], p[0], p[3])
+		}
+	}
+
+	for y := h - 1; y >= 0; y-- {
+		row := make([]byte, maskStride)
+		for x := 0; x < w; x++ {
+			if rgba[(y*w+x)*4+3] == 0 {
+				row[x/8] |= 0x80 >> (x % 8)
+			}
+		}
+
+		buf = append(buf, row...)
+	}
+
+	return buf
+}
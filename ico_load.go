@@ -0,0 +1,300 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+)
+
+var ErrIcoSizeNotFound = fmt.Errorf("icon has no entry of the requested size")
+
+type icoDirEntry struct {
+	width, height int
+	bpp           int
+	data          []byte
+}
+
+// LoadIco decodes an entry of an ICO or CUR image. The entry which longest side equals size is picked,
+// the one with the most colors if there are several. Size 0 picks the largest entry.
+func LoadIco(buf []byte, size int) (*VipsImage, error) {
+	return loadIco(buf, size, LoadOptions{})
+}
+
+// loadIco checks the size limits of opts against the entry header, before the entry is decoded
+func loadIco(buf []byte, size int, opts LoadOptions) (*VipsImage, error) {
+	entries, err := parseIcoDir(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := pickIcoEntry(entries, size)
+	if err != nil {
+		return nil, err
+	}
+
+	// The loader reads only the header of the PNG entry to check the limits. The pixels are read lazily,
+	// so the entry is decoded while buf is still in use.
+	if bytes.HasPrefix(entry.data, pngMagic) {
+		img, err := LoadWithOptions(entry.data, opts.limits())
+		if err != nil {
+			return nil, err
+		}
+
+		if err = img.CopyMemory(); err != nil {
+			img.Clear()
+			return nil, err
+		}
+
+		return img, nil
+	}
+
+	w, h, err := icoDIBSize(entry.data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = opts.checkSize(w, h); err != nil {
+		return nil, err
+	}
+
+	w, h, rgba, err := decodeIcoDIB(entry.data)
+	if err != nil {
+		return nil, err
+	}
+
+	img := C.vips_image_new_from_rgba_go(unsafe.Pointer(&rgba[0]), C.size_t(len(rgba)), C.int(w), C.int(h))
+	if img == nil {
+		return nil, vipsError("LoadIco")
+	}
+
+	return &VipsImage{img}, nil
+}
+
+func parseIcoDir(buf []byte) ([]icoDirEntry, error) {
+	if !isIcoHeader(buf) {
+		return nil, fmt.Errorf("%w: invalid icon header", ErrCorruptImage)
+	}
+
+	count := int(binary.LittleEndian.Uint16(buf[4:]))
+	if len(buf) < icoDirSize+icoDirEntrySize*count {
+		return nil, fmt.Errorf("%w: truncated icon directory", ErrCorruptImage)
+	}
+
+	entries := make([]icoDirEntry, 0, count)
+	for i := 0; i < count; i++ {
+		e := buf[icoDirSize+icoDirEntrySize*i:]
+
+		size := int(binary.LittleEndian.Uint32(e[8:]))
+		offset := int(binary.LittleEndian.Uint32(e[12:]))
+		if size <= 0 || offset < 0 || offset > len(buf) || size > len(buf)-offset {
+			return nil, fmt.Errorf("%w: icon entry %d is out of the file", ErrCorruptImage, i)
+		}
+
+		entry := icoDirEntry{
+			width:  int(e[0]),
+			height: int(e[1]),
+			bpp:    int(binary.LittleEndian.Uint16(e[6:])),
+			data:   buf[offset : offset+size],
+		}
+
+		// 0 means 256 pixels
+		if entry.width == 0 {
+			entry.width = icoMaxSize
+		}
+		if entry.height == 0 {
+			entry.height = icoMaxSize
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func pickIcoEntry(entries []icoDirEntry, size int) (icoDirEntry, error) {
+	best := -1
+	bestSize := 0
+
+	for i, e := range entries {
+		longest := e.width
+		if e.height > longest {
+			longest = e.height
+		}
+
+		if size > 0 && longest != size {
+			continue
+		}
+
+		if best < 0 || longest > bestSize || longest == bestSize && e.bpp > entries[best].bpp {
+			best, bestSize = i, longest
+		}
+	}
+
+	if best < 0 {
+		return icoDirEntry{}, fmt.Errorf("%w: %d", ErrIcoSizeNotFound, size)
+	}
+
+	return entries[best], nil
+}
+
+// icoDIBSize reads the dimensions of a BMP entry from its header
+func icoDIBSize(data []byte) (int, int, error) {
+	if len(data) < dibHeaderSize {
+		return 0, 0, fmt.Errorf("%w: truncated bitmap header", ErrCorruptImage)
+	}
+
+	headerSize := int(binary.LittleEndian.Uint32(data[0:]))
+	w := int(int32(binary.LittleEndian.Uint32(data[4:])))
+	// The height covers both the XOR bitmap and the AND mask
+	h := int(int32(binary.LittleEndian.Uint32(data[8:]))) / 2
+
+	if headerSize < dibHeaderSize || headerSize > len(data) {
+		return 0, 0, fmt.Errorf("%w: invalid bitmap header size %d", ErrCorruptImage, headerSize)
+	}
+
+	if w <= 0 || h <= 0 || w > icoMaxSize || h > icoMaxSize {
+		return 0, 0, fmt.Errorf("%w: invalid bitmap dimensions %dx%d", ErrCorruptImage, w, h)
+	}
+
+	return w, h, nil
+}
+
+// decodeIcoDIB decodes a BMP entry of 1, 4, 8, 24 or 32 bits per pixel into RGBA. Pixels set in the AND mask
+// are transparent unless the entry has its own alpha channel.
+func decodeIcoDIB(data []byte) (int, int, []byte, error) {
+	w, h, err := icoDIBSize(data)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	headerSize := int(binary.LittleEndian.Uint32(data[0:]))
+	bpp := int(binary.LittleEndian.Uint16(data[14:]))
+	compression := binary.LittleEndian.Uint32(data[16:])
+	colors := int(binary.LittleEndian.Uint32(data[32:]))
+
+	if compression != 0 {
+		return 0, 0, nil, fmt.Errorf("unsupported icon bitmap compression %d", compression)
+	}
+
+	var palette []byte
+
+	switch bpp {
+	case 1, 4, 8:
+		if colors == 0 || colors > 1<<bpp {
+			colors = 1 << bpp
+		}
+
+		if len(data) < headerSize+colors*4 {
+			return 0, 0, nil, fmt.Errorf("%w: truncated bitmap palette", ErrCorruptImage)
+		}
+
+		palette = data[headerSize : headerSize+colors*4]
+	case 24, 32:
+	default:
+		return 0, 0, nil, fmt.Errorf("unsupported icon bitmap depth %d", bpp)
+	}
+
+	pixels := data[headerSize+len(palette):]
+
+	stride := (w*bpp + 31) / 32 * 4
+	maskStride := (w + 31) / 32 * 4
+
+	if len(pixels) < stride*h {
+		return 0, 0, nil, fmt.Errorf("%w: truncated bitmap data", ErrCorruptImage)
+	}
+
+	mask := pixels[stride*h:]
+	if len(mask) < maskStride*h {
+		// 32-bit entries are often written without the mask
+		if bpp != 32 {
+			return 0, 0, nil, fmt.Errorf("%w: truncated bitmap mask", ErrCorruptImage)
+		}
+		mask = nil
+	}
+
+	rgba := make([]byte, w*h*4)
+	hasAlpha := false
+
+	for y := 0; y < h; y++ {
+		// Rows are stored bottom-up
+		row := pixels[(h-1-y)*stride:]
+
+		for x := 0; x < w; x++ {
+			p := rgba[(y*w+x)*4:]
+
+			switch bpp {
+			case 1, 4, 8:
+				idx := int(row[x*bpp/8]>>(8-bpp-x*bpp%8)) & (1<<bpp - 1)
+				if idx < colors {
+					c := palette[idx*4:]
+					p[0], p[1], p[2] = c[2], c[1], c[0]
+				}
+				p[3] = 255
+			case 24:
+				p[0], p[1], p[2], p[3] = row[x*3+2], row[x*3+1], row[x*3], 255
+			case 32:
+				p[0], p[1], p[2], p[3] = row[x*4+2], row[x*4+1], row[x*4], row[x*4+3]
+				hasAlpha = hasAlpha || p[3] != 0
+			}
+		}
+	}
+
+	if bpp == 32 && hasAlpha {
+		return w, h, rgba, nil
+	}
+
+	if mask == nil {
+		for i := 3; i < len(rgba); i += 4 {
+			rgba[i] = 255
+		}
+
+		return w, h, rgba, nil
+	}
+
+	for y := 0; y < h; y++ {
+		row := mask[(h-1-y)*maskStride:]
+
+		for x := 0; x < w; x++ {
+			p := rgba[(y*w+x)*4:]
+			if row[x/8]&(0x80>>(x%8)) != 0 {
+				p[3] = 0
+			} else {
+				p[3] = 255
+			}
+		}
+	}
+
+	return w, h, rgba, nil
+}
@@ -0,0 +1,225 @@
+package libvips_go
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestParseIcoDir(t *testing.T) {
+	buf, err := os.ReadFile(".test/blank.ico")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := parseIcoDir(buf)
+	if err != nil {
+		t.Fatalf("parseIcoDir() error = %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].width != 1 || entries[0].height != 1 || entries[0].bpp != 32 {
+		t.Fatalf("parseIcoDir() got = %+v, want one 1x1 32-bit entry", entries)
+	}
+
+	w, h, rgba, err := decodeIcoDIB(entries[0].data)
+	if err != nil {
+		t.Fatalf("decodeIcoDIB() error = %v", err)
+	}
+	if w != 1 || h != 1 || !bytes.Equal(rgba, []byte{255, 255, 255, 255}) {
+		t.Errorf("decodeIcoDIB() got = %dx%d %v, want 1x1 opaque white", w, h, rgba)
+	}
+
+	truncated := append([]byte{}, buf...)
+	binary.LittleEndian.PutUint32(truncated[icoDirSize+8:], uint32(len(buf)))
+	if _, err := parseIcoDir(truncated); !errors.Is(err, ErrCorruptImage) {
+		t.Errorf("parseIcoDir() error = %v, want ErrCorruptImage", err)
+	}
+}
+
+func TestPickIcoEntry(t *testing.T) {
+	entries := []icoDirEntry{
+		{width: 16, height: 16, bpp: 8},
+		{width: 32, height: 32, bpp: 8},
+		{width: 32, height: 32, bpp: 32},
+		{width: 256, height: 256, bpp: 32},
+		{width: 48, height: 24, bpp: 32},
+	}
+
+	tests := []struct {
+		name    string
+		size    int
+		want    int
+		wantErr error
+	}{
+		{"Largest", 0, 3, nil},
+		{"ExactSize", 16, 0, nil},
+		{"MostColors", 32, 2, nil},
+		{"LongestSide", 48, 4, nil},
+		{"NotFound", 64, 0, ErrIcoSizeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pickIcoEntry(entries, tt.size)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("pickIcoEntry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, entries[tt.want]) {
+				t.Errorf("pickIcoEntry() got = %+v, want %+v", got, entries[tt.want])
+			}
+		})
+	}
+}
+
+func TestDecodeIcoDIB(t *testing.T) {
+	// 3x2 with a transparent pixel, written by the ICO encoder
+	rgba := []byte{
+		255, 0, 0, 255, 0, 255, 0, 128, 0, 0, 255, 255,
+		10, 20, 30, 0, 40, 50, 60, 255, 70, 80, 90, 255,
+	}
+
+	// 1-bit 2x2 with black and white palette, the bottom right pixel is masked out
+	mono := make([]byte, dibHeaderSize, dibHeaderSize+8+8+8)
+	binary.LittleEndian.PutUint32(mono[0:], dibHeaderSize)
+	binary.LittleEndian.PutUint32(mono[4:], 2)
+	binary.LittleEndian.PutUint32(mono[8:], 4)
+	binary.LittleEndian.PutUint16(mono[12:], 1)
+	binary.LittleEndian.PutUint16(mono[14:], 1)
+	mono = append(mono, 0, 0, 0, 0, 255, 255, 255, 0)
+	// XOR rows bottom-up: bottom row is white, black; top row is black, white
+	mono = append(mono, 0x80, 0, 0, 0, 0x40, 0, 0, 0)
+	// AND rows bottom-up
+	mono = append(mono, 0x40, 0, 0, 0, 0, 0, 0, 0)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantW    int
+		wantH    int
+		wantRGBA []byte
+		wantErr  bool
+	}{
+		{"RoundTrip32", encodeIcoDIB(3, 2, rgba), 3, 2, rgba, false},
+		{"Paletted1WithMask", mono, 2, 2, []byte{
+			0, 0, 0, 255, 255, 255, 255, 255,
+			255, 255, 255, 255, 0, 0, 0, 0,
+		}, false},
+		{"Truncated", encodeIcoDIB(3, 2, rgba)[:dibHeaderSize+8], 0, 0, nil, true},
+		{"TooShort", []byte{40, 0, 0, 0}, 0, 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h, got, err := decodeIcoDIB(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeIcoDIB() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if w != tt.wantW || h != tt.wantH || !bytes.Equal(got, tt.wantRGBA) {
+				t.Errorf("decodeIcoDIB() got = %dx%d %v, want %dx%d %v", w, h, got, tt.wantW, tt.wantH, tt.wantRGBA)
+			}
+		})
+	}
+}
+
+// wrapIco wraps the PNG or BMP data into a single entry icon
+func wrapIco(data []byte, w, h int) []byte {
+	buf := make([]byte, icoDirSize+icoDirEntrySize, icoDirSize+icoDirEntrySize+len(data))
+	binary.LittleEndian.PutUint16(buf[2:], 1)
+	binary.LittleEndian.PutUint16(buf[4:], 1)
+	buf[icoDirSize] = byte(w)
+	buf[icoDirSize+1] = byte(h)
+	binary.LittleEndian.PutUint16(buf[icoDirSize+4:], 1)
+	binary.LittleEndian.PutUint16(buf[icoDirSize+6:], 32)
+	binary.LittleEndian.PutUint32(buf[icoDirSize+8:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(buf[icoDirSize+12:], icoDirSize+icoDirEntrySize)
+
+	return append(buf, data...)
+}
+
+func TestLoadIco(t *testing.T) {
+	startVips(t)
+
+	tests := []struct {
+		name  string
+		buf   []byte
+		wantW int
+		wantH int
+	}{
+		{"DIB", readFixture(t, "blank.ico"), 1, 1},
+		{"PNG", wrapIco(readFixture(t, "blank.png"), 3, 2), 3, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := append([]byte{}, tt.buf...)
+
+			img, err := LoadIco(buf, 0)
+			if err != nil {
+				t.Fatalf("LoadIco() error = %v", err)
+			}
+			defer img.Clear()
+
+			// The image must not refer to the input once it's loaded
+			for i := range buf {
+				buf[i] = 0
+			}
+			buf = nil
+			runtime.GC()
+
+			if img.Width() != tt.wantW || img.Height() != tt.wantH {
+				t.Errorf("LoadIco() got = %dx%d, want %dx%d", img.Width(), img.Height(), tt.wantW, tt.wantH)
+			}
+
+			if _, err = img.Save(PNG, DefaultEncodeConfig); err != nil {
+				t.Errorf("Save() error = %v", err)
+			}
+		})
+	}
+}
+
+// hugePNG claims w x h pixels in the header of the 3x2 fixture
+func hugePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	png := append([]byte{}, readFixture(t, "blank.png")...)
+
+	// IHDR data follows the signature, the chunk length and type
+	ihdr := png[16:29]
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(w))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(h))
+	binary.BigEndian.PutUint32(png[29:], crc32.ChecksumIEEE(png[12:29]))
+
+	return png
+}
+
+func TestLoadWithOptions_IcoLimits(t *testing.T) {
+	// BMP entry header of 48x32, without the pixels
+	dib := make([]byte, dibHeaderSize)
+	binary.LittleEndian.PutUint32(dib[0:], dibHeaderSize)
+	binary.LittleEndian.PutUint32(dib[4:], 48)
+	binary.LittleEndian.PutUint32(dib[8:], 2*32)
+
+	tests := []struct {
+		name string
+		buf  func(t *testing.T) []byte
+		opts LoadOptions
+		vips bool
+	}{
+		{"BMP", func(*testing.T) []byte { return wrapIco(dib, 48, 32) }, LoadOptions{MaxWidth: 16}, false},
+		{"PNG", func(t *testing.T) []byte { return wrapIco(hugePNG(t, 30000, 30000), 0, 0) },
+			LoadOptions{MaxPixels: 1 << 24}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.vips {
+				startVips(t)
+			}
+
+			if _, err := LoadWithOptions(tt.buf(t), tt.opts); !errors.Is(err, ErrImageTooLarge) {
+				t.Errorf("LoadWithOptions() error = %v, want ErrImageTooLarge", err)
+			}
+		})
+	}
+}
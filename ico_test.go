@@ -0,0 +1,160 @@
+package libvips_go
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"testing"
+)
+
+func TestEncodeIco(t *testing.T) {
+	entries := []icoEntry{
+		{width: 16, height: 16, planes: 1, bpp: 32, data: []byte{1, 2, 3}},
+		{width: 256, height: 128, planes: 1, bpp: 32, data: []byte{4, 5}},
+	}
+
+	tests := []struct {
+		name   string
+		kind   uint16
+		format ImageFormat
+	}{
+		{"Icon", icoTypeIcon, ICO},
+		{"Cursor", icoTypeCursor, ICO},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeIco(tt.kind, entries)
+			if err != nil {
+				t.Fatalf("encodeIco() error = %v", err)
+			}
+
+			if f := FormatByMagicNumber(got); f != tt.format {
+				t.Errorf("FormatByMagicNumber() = %v, want %v", f, tt.format)
+			}
+
+			if kind := binary.LittleEndian.Uint16(got[2:]); kind != tt.kind {
+				t.Errorf("encodeIco() type = %d, want %d", kind, tt.kind)
+			}
+
+			if n := binary.LittleEndian.Uint16(got[4:]); n != 2 {
+				t.Fatalf("encodeIco() entries = %d, want 2", n)
+			}
+
+			second := got[icoDirSize+icoDirEntrySize:]
+			if second[0] != 0 || second[1] != 128 {
+				t.Errorf("encodeIco() second entry dimensions = %dx%d, want 0x128", second[0], second[1])
+			}
+
+			offset := binary.LittleEndian.Uint32(second[12:])
+			if want := uint32(icoDirSize + 2*icoDirEntrySize + 3); offset != want {
+				t.Errorf("encodeIco() second entry offset = %d, want %d", offset, want)
+			}
+
+			if !bytes.Equal(got[offset:], []byte{4, 5}) {
+				t.Errorf("encodeIco() second entry data = %v, want [4 5]", got[offset:])
+			}
+		})
+	}
+}
+
+func TestEncodeIcoDIB(t *testing.T) {
+	// 2x2: opaque red, transparent green on the top row, opaque blue, opaque white on the bottom one
+	rgba := []byte{
+		255, 0, 0, 255, 0, 255, 0, 0,
+		0, 0, 255, 255, 255, 255, 255, 255,
+	}
+
+	got := encodeIcoDIB(2, 2, rgba)
+
+	if want := dibHeaderSize + 2*2*4 + 2*4; len(got) != want {
+		t.Fatalf("encodeIcoDIB() size = %d, want %d", len(got), want)
+	}
+
+	if h := binary.LittleEndian.Uint32(got[8:]); h != 4 {
+		t.Errorf("encodeIcoDIB() height = %d, want doubled 4", h)
+	}
+
+	// Rows are stored bottom-up in BGRA
+	wantPixels := []byte{
+		255, 0, 0, 255, 255, 255, 255, 255,
+		0, 0, 255, 255, 0, 255, 0, 0,
+	}
+	if pix := got[dibHeaderSize : dibHeaderSize+16]; !bytes.Equal(pix, wantPixels) {
+		t.Errorf("encodeIcoDIB() pixels = %v, want %v", pix, wantPixels)
+	}
+
+	wantMask := []byte{0, 0, 0, 0, 0x40, 0, 0, 0}
+	if mask := got[dibHeaderSize+16:]; !bytes.Equal(mask, wantMask) {
+		t.Errorf("encodeIcoDIB() mask = %v, want %v", mask, wantMask)
+	}
+}
+
+func TestVipsImage_SaveIco(t *testing.T) {
+	tests := []struct {
+		name    string
+		sizes   []int
+		opts    IcoOptions
+		hotspot *image.Point
+		kind    uint16
+	}{
+		{"Icon", DefaultIcoSizes, IcoOptions{}, nil, icoTypeIcon},
+		{"IconWithBMP", DefaultIcoSizes, IcoOptions{BMPMaxSize: 48}, nil, icoTypeIcon},
+		{"Cursor", []int{32, 64}, IcoOptions{BMPMaxSize: 32}, &image.Point{X: 600, Y: 900}, icoTypeCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The image is read sequentially, as loaded
+			img := loadFixture(t, "wiki_a4.png")
+
+			var buf []byte
+			var err error
+			if tt.hotspot != nil {
+				buf, err = img.SaveCur(tt.sizes, *tt.hotspot, tt.opts)
+			} else {
+				buf, err = img.SaveIco(tt.sizes, tt.opts)
+			}
+			if err != nil {
+				t.Fatalf("save error = %v", err)
+			}
+
+			if kind := binary.LittleEndian.Uint16(buf[2:]); kind != tt.kind {
+				t.Errorf("type = %d, want %d", kind, tt.kind)
+			}
+
+			entries, err := parseIcoDir(buf)
+			if err != nil {
+				t.Fatalf("parseIcoDir() error = %v", err)
+			}
+
+			if len(entries) != len(tt.sizes) {
+				t.Fatalf("got %d entries, want %d", len(entries), len(tt.sizes))
+			}
+
+			for i, e := range entries {
+				// The A4 page is higher than wide
+				if e.height != tt.sizes[i] {
+					t.Errorf("entry %d height = %d, want %d", i, e.height, tt.sizes[i])
+				}
+
+				if isPNG := bytes.HasPrefix(e.data, pngMagic); isPNG == (tt.sizes[i] <= tt.opts.BMPMaxSize) {
+					t.Errorf("entry %d of size %d is PNG %v, want BMP up to %d", i, tt.sizes[i], isPNG, tt.opts.BMPMaxSize)
+				}
+
+				decoded, err := LoadIco(buf, tt.sizes[i])
+				if err != nil {
+					t.Fatalf("LoadIco(%d) error = %v", tt.sizes[i], err)
+				}
+
+				if decoded.Height() != tt.sizes[i] {
+					t.Errorf("LoadIco(%d) height = %d", tt.sizes[i], decoded.Height())
+				}
+				decoded.Clear()
+			}
+
+			// The image stays usable
+			if _, err = img.Save(PNG, DefaultEncodeConfig); err != nil {
+				t.Errorf("Save() after the icon error = %v", err)
+			}
+		})
+	}
+}
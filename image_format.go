@@ -32,6 +32,8 @@ package libvips_go
 */
 import "C"
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"strings"
 )
@@ -52,10 +54,13 @@ const (
 	SVG     = ImageFormat(C.SVG)
 	TIFF    = ImageFormat(C.TIFF)
 	WEBP    = ImageFormat(C.WEBP)
+	JXL     = ImageFormat(C.JXL)
+	JP2K    = ImageFormat(C.JP2K)
 
 	HEIF_COMPRESSION_HEVC = HEIFCompressionType(C.VIPS_FOREIGN_HEIF_COMPRESSION_HEVC)
 	HEIF_COMPRESSION_AVC  = HEIFCompressionType(C.VIPS_FOREIGN_HEIF_COMPRESSION_AVC)
 	HEIF_COMPRESSION_JPEG = HEIFCompressionType(C.VIPS_FOREIGN_HEIF_COMPRESSION_JPEG)
+	HEIF_COMPRESSION_AV1  = HEIFCompressionType(C.VIPS_FOREIGN_HEIF_COMPRESSION_AV1)
 )
 
 func (imgFmt ImageFormat) Ext() string {
@@ -88,8 +93,12 @@ func (imgFmt ImageFormat) MarshalText() ([]byte, error) {
 		return []byte("heif"), nil
 	case ICO:
 		return []byte("ico"), nil
+	case JP2K:
+		return []byte("jp2"), nil
 	case JPEG:
 		return []byte("jpg"), nil
+	case JXL:
+		return []byte("jxl"), nil
 	case PDF:
 		return []byte("pdf"), nil
 	case PNG:
@@ -124,8 +133,12 @@ func (imgFmt *ImageFormat) UnmarshalText(val []byte) error {
 		*imgFmt = HEIF
 	case "ico":
 		*imgFmt = ICO
+	case "jp2", "j2k", "j2c", "jpf", "jpx":
+		*imgFmt = JP2K
 	case "jpg", "jpeg":
 		*imgFmt = JPEG
+	case "jxl":
+		*imgFmt = JXL
 	case "pdf":
 		*imgFmt = PDF
 	case "png":
@@ -143,48 +156,157 @@ func (imgFmt *ImageFormat) UnmarshalText(val []byte) error {
 	return nil
 }
 
+// Number of leading bytes FormatByMagicNumber looks at. SVG may start with a long XML prolog
+const magicNumberSniffLen = 1024
+
+var (
+	jpegMagic    = []byte{0xFF, 0xD8, 0xFF}
+	pngMagic     = []byte{0x89, 0x50, 0x4E, 0x47}
+	gifMagic     = []byte("GIF8")
+	riffMagic    = []byte("RIFF")
+	webpMagic    = []byte("WEBP")
+	pdfMagic     = []byte("%PDF-")
+	bmpMagic     = []byte("BM")
+	tiffLEMagic  = []byte{0x49, 0x49, 0x2A, 0x00}
+	tiffBEMagic  = []byte{0x4D, 0x4D, 0x00, 0x2A}
+	bigTiffLE    = []byte{0x49, 0x49, 0x2B, 0x00}
+	bigTiffBE    = []byte{0x4D, 0x4D, 0x00, 0x2B}
+	ftypMagic    = []byte("ftyp")
+	jxlMagic     = []byte{0xFF, 0x0A}
+	jxlBoxMagic  = []byte{0x00, 0x00, 0x00, 0x0C, 0x4A, 0x58, 0x4C, 0x20, 0x0D, 0x0A, 0x87, 0x0A}
+	jp2BoxMagic  = []byte{0x00, 0x00, 0x00, 0x0C, 0x6A, 0x50, 0x20, 0x20, 0x0D, 0x0A, 0x87, 0x0A}
+	j2kMagic     = []byte{0xFF, 0x4F, 0xFF, 0x51}
+	utf8BOMMagic = []byte{0xEF, 0xBB, 0xBF}
+)
+
 func FormatByMagicNumber(buf []byte) ImageFormat {
-	if buf[0] == 0xFF && buf[1] == 0xD8 && buf[2] == 0xFF {
+	if bytes.HasPrefix(buf, jpegMagic) {
 		return JPEG
 	}
 
-	if buf[0] == 0x89 && buf[1] == 0x50 && buf[2] == 0x4E && buf[3] == 0x47 {
+	if bytes.HasPrefix(buf, pngMagic) {
 		return PNG
 	}
 
-	if buf[0] == 0x47 && buf[1] == 0x49 && buf[2] == 0x46 {
+	if bytes.HasPrefix(buf, gifMagic) {
 		return GIF
 	}
 
-	if buf[8] == 0x57 && buf[9] == 0x45 && buf[10] == 0x42 && buf[11] == 0x50 {
+	if bytes.HasPrefix(buf, riffMagic) && len(buf) >= 12 && bytes.Equal(buf[8:12], webpMagic) {
 		return WEBP
 	}
 
-	if buf[0] == 0x25 && buf[1] == 0x50 && buf[2] == 0x44 && buf[3] == 0x46 && buf[4] == 0x2D {
+	if bytes.HasPrefix(buf, pdfMagic) {
 		return PDF
 	}
 
-	if buf[0] == 0x42 && buf[1] == 0x4D {
+	if bytes.HasPrefix(buf, bmpMagic) {
 		return BMP
 	}
 
-	if buf[0] == 0x00 && buf[1] == 0x00 && buf[2] == 0x01 && buf[3] == 0x00 {
+	if isIcoHeader(buf) {
 		return ICO
 	}
 
-	if (buf[0] == 0x49 && buf[1] == 0x49 && buf[2] == 0x2A && buf[3] == 0x0) ||
-		(buf[0] == 0x4D && buf[1] == 0x4D && buf[2] == 0x0 && buf[3] == 0x2A) {
+	if bytes.HasPrefix(buf, tiffLEMagic) || bytes.HasPrefix(buf, tiffBEMagic) ||
+		bytes.HasPrefix(buf, bigTiffLE) || bytes.HasPrefix(buf, bigTiffBE) {
 		return TIFF
 	}
 
-	//like MPEG-4 video files
-	if buf[3] == 0x18 && buf[4] == 0x66 && buf[5] == 0x74 && buf[6] == 0x79 && buf[7] == 0x70 {
-		return HEIF
+	// HEIF and AVIF are ISOBMFF containers like MPEG-4 video files
+	if imgFmt := formatByFtypBrands(buf); imgFmt != Unknown {
+		return imgFmt
+	}
+
+	if bytes.HasPrefix(buf, jxlMagic) || bytes.HasPrefix(buf, jxlBoxMagic) {
+		return JXL
+	}
+
+	if bytes.HasPrefix(buf, jp2BoxMagic) || bytes.HasPrefix(buf, j2kMagic) {
+		return JP2K
+	}
+
+	if isSVG(buf) {
+		return SVG
+	}
+
+	return Unknown
+}
+
+// isIcoHeader checks ICONDIR of icons (type 1) and cursors (type 2) and the first ICONDIRENTRY,
+// as four bytes of the header alone match too many other files
+func isIcoHeader(buf []byte) bool {
+	if len(buf) < 22 || buf[0] != 0 || buf[1] != 0 || (buf[2] != 1 && buf[2] != 2) || buf[3] != 0 {
+		return false
+	}
+
+	count := int(binary.LittleEndian.Uint16(buf[4:6]))
+	if count == 0 {
+		return false
+	}
+
+	// Reserved byte of the entry is always zero
+	if buf[9] != 0 {
+		return false
+	}
+
+	size := binary.LittleEndian.Uint32(buf[14:18])
+	offset := binary.LittleEndian.Uint32(buf[18:22])
+
+	return size > 0 && offset >= uint32(6+16*count)
+}
+
+// formatByFtypBrands looks through the major and compatible brands of the ftyp box,
+// since HEIF and AVIF files differ only by them
+func formatByFtypBrands(buf []byte) ImageFormat {
+	if len(buf) < 16 || !bytes.Equal(buf[4:8], ftypMagic) {
+		return Unknown
+	}
+
+	end := int(binary.BigEndian.Uint32(buf[0:4]))
+	if end < 16 {
+		return Unknown
+	}
+
+	if end > len(buf) {
+		end = len(buf)
+	}
+
+	brands := [][]byte{buf[8:12]}
+	for i := 16; i+4 <= end; i += 4 {
+		brands = append(brands, buf[i:i+4])
 	}
 
-	if buf[3] == 0x1C && buf[4] == 0x66 && buf[5] == 0x74 && buf[6] == 0x79 && buf[7] == 0x70 {
-		return AVIF
+	heif := false
+	for _, brand := range brands {
+		switch string(brand) {
+		case "avif", "avis":
+			return AVIF
+		case "heic", "heix", "heim", "heis", "hevc", "hevx", "mif1", "msf1":
+			heif = true
+		}
+	}
+
+	if heif {
+		return HEIF
 	}
 
 	return Unknown
 }
+
+func isSVG(buf []byte) bool {
+	if len(buf) > magicNumberSniffLen {
+		buf = buf[:magicNumberSniffLen]
+	}
+
+	buf = bytes.TrimPrefix(buf, utf8BOMMagic)
+	buf = bytes.TrimLeft(buf, " \t\r\n")
+
+	// The root element may follow an XML declaration, comments or a doctype
+	if !bytes.HasPrefix(buf, []byte("<svg")) && !bytes.HasPrefix(buf, []byte("<?xml")) &&
+		!bytes.HasPrefix(buf, []byte("<!")) {
+		return false
+	}
+
+	return bytes.Contains(buf, []byte("<svg"))
+}
@@ -2,6 +2,7 @@ package libvips_go
 
 import (
 	"io/ioutil"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -38,6 +39,118 @@ func TestFormatByMagicNumber(t *testing.T) {
 	}
 }
 
+func TestFormatByMagicNumber_Signatures(t *testing.T) {
+	tests := []struct {
+		name string
+		buf  []byte
+		want ImageFormat
+	}{
+		{"Empty", nil, Unknown},
+		{"OneByte", []byte{0xFF}, Unknown},
+		{"ShortRIFF", []byte("RIFF\x00\x00"), Unknown},
+		{"ShortFtyp", []byte("\x00\x00\x00\x18ftyp"), Unknown},
+		{"ShortIco", []byte{0, 0, 1, 0, 1, 0}, Unknown},
+		{"FtypHeicMajor", ftyp("heic", "mif1", "heic"), HEIF},
+		{"FtypHeix", ftyp("heix", "mif1"), HEIF},
+		{"FtypMif1Only", ftyp("mif1", "mif1"), HEIF},
+		{"FtypAvifMajor", ftyp("avif", "mif1", "miaf"), AVIF},
+		{"FtypAvifCompatible", ftyp("mif1", "mif1", "avif", "miaf"), AVIF},
+		{"FtypAvis", ftyp("avis", "msf1", "miaf"), AVIF},
+		{"FtypMP4", ftyp("isom", "isom", "iso2", "mp41"), Unknown},
+		{"FtypTruncatedBrands", ftyp("mif1", "mif1", "avif")[:20], HEIF},
+		{"ICO", []byte{0, 0, 1, 0, 1, 0, 16, 16, 0, 0, 1, 0, 32, 0, 4, 0, 0, 0, 22, 0, 0, 0}, ICO},
+		{"CUR", []byte{0, 0, 2, 0, 1, 0, 16, 16, 0, 0, 4, 0, 4, 0, 4, 0, 0, 0, 22, 0, 0, 0}, ICO},
+		{"ICOWithoutImages", []byte{0, 0, 1, 0, 0, 0, 16, 16, 0, 0, 1, 0, 32, 0, 4, 0, 0, 0, 22, 0, 0, 0}, Unknown},
+		{"ICOBadOffset", []byte{0, 0, 1, 0, 1, 0, 16, 16, 0, 0, 1, 0, 32, 0, 4, 0, 0, 0, 2, 0, 0, 0}, Unknown},
+		{"BigTIFF", []byte{0x49, 0x49, 0x2B, 0x00, 0x08, 0x00}, TIFF},
+		{"JXLCodestream", []byte{0xFF, 0x0A, 0xFA, 0x7F}, JXL},
+		{"JXLContainer", []byte{0, 0, 0, 0x0C, 'J', 'X', 'L', ' ', 0x0D, 0x0A, 0x87, 0x0A, 0, 0, 0, 0x14}, JXL},
+		{"JP2", []byte{0, 0, 0, 0x0C, 'j', 'P', ' ', ' ', 0x0D, 0x0A, 0x87, 0x0A, 0, 0, 0, 0x14}, JP2K},
+		{"J2K", []byte{0xFF, 0x4F, 0xFF, 0x51, 0x00, 0x2F}, JP2K},
+		{"SVG", []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`), SVG},
+		{"SVGWithProlog", []byte("\xEF\xBB\xBF\n<?xml version=\"1.0\"?>\n<!-- logo -->\n<svg width=\"10\"/>"), SVG},
+		{"SVGWithDoctype", []byte(`<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "x.dtd"><svg/>`), SVG},
+		{"XMLNotSVG", []byte(`<?xml version="1.0"?><html></html>`), Unknown},
+		{"TextMentioningSVG", []byte(`not xml <svg>`), Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatByMagicNumber(tt.buf); got != tt.want {
+				t.Errorf("FormatByMagicNumber() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ftyp(major string, compatible ...string) []byte {
+	size := 16 + 4*len(compatible)
+
+	buf := []byte{0, 0, 0, byte(size)}
+	buf = append(buf, "ftyp"+major+"\x00\x00\x00\x00"...)
+	for _, brand := range compatible {
+		buf = append(buf, brand...)
+	}
+
+	return append(buf, "\x00\x00\x00\x08meta"...)
+}
+
+func FuzzFormatByMagicNumber(f *testing.F) {
+	files, err := filepath.Glob(".test/blank.*")
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, file := range files {
+		buf, err := ioutil.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		f.Add(buf)
+		for i := 0; i < len(buf) && i < 32; i++ {
+			f.Add(buf[:i])
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		imgFmt := FormatByMagicNumber(buf)
+		if imgFmt != Unknown && imgFmt.String() == "Unknown" {
+			t.Errorf("FormatByMagicNumber() = %d, not a known format", imgFmt)
+		}
+	})
+}
+
+func TestImageFormat_Values(t *testing.T) {
+	// The values are stored by the users, new formats are appended
+	tests := []struct {
+		name   string
+		imgFmt ImageFormat
+		want   int
+	}{
+		{"Unknown", Unknown, 0},
+		{"AVIF", AVIF, 1},
+		{"BMP", BMP, 2},
+		{"GIF", GIF, 3},
+		{"HEIF", HEIF, 4},
+		{"ICO", ICO, 5},
+		{"JPEG", JPEG, 6},
+		{"PDF", PDF, 7},
+		{"PNG", PNG, 8},
+		{"SVG", SVG, 9},
+		{"TIFF", TIFF, 10},
+		{"WEBP", WEBP, 11},
+		{"JXL", JXL, 12},
+		{"JP2K", JP2K, 13},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if int(tt.imgFmt) != tt.want {
+				t.Errorf("%s = %d, want %d", tt.name, tt.imgFmt, tt.want)
+			}
+		})
+	}
+}
+
 func TestImageFormat_MarshalText(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -50,7 +163,9 @@ func TestImageFormat_MarshalText(t *testing.T) {
 		{"MarshalTextGIF", GIF, []byte("gif"), false},
 		{"MarshalTextHEIF", HEIF, []byte("heif"), false},
 		{"MarshalTextICO", ICO, []byte("ico"), false},
+		{"MarshalTextJP2K", JP2K, []byte("jp2"), false},
 		{"MarshalTextJPEG", JPEG, []byte("jpg"), false},
+		{"MarshalTextJXL", JXL, []byte("jxl"), false},
 		{"MarshalTextPDF", PDF, []byte("pdf"), false},
 		{"MarshalTextPNG", PNG, []byte("png"), false},
 		{"MarshalTextSVG", SVG, []byte("svg"), false},
@@ -84,7 +199,9 @@ func TestImageFormat_Ext(t *testing.T) {
 		{"ExtensionGIF", GIF, ".gif"},
 		{"ExtensionHEIF", HEIF, ".heif"},
 		{"ExtensionICO", ICO, ".ico"},
+		{"ExtensionJP2K", JP2K, ".jp2"},
 		{"ExtensionJPEG", JPEG, ".jpg"},
+		{"ExtensionJXL", JXL, ".jxl"},
 		{"ExtensionPDF", PDF, ".pdf"},
 		{"ExtensionPNG", PNG, ".png"},
 		{"ExtensionSVG", SVG, ".svg"},
@@ -113,7 +230,9 @@ func TestImageFormat_String(t *testing.T) {
 		{"StringGIF", GIF, "gif"},
 		{"StringHEIF", HEIF, "heif"},
 		{"StringICO", ICO, "ico"},
+		{"StringJP2K", JP2K, "jp2"},
 		{"StringJPEG", JPEG, "jpg"},
+		{"StringJXL", JXL, "jxl"},
 		{"StringPDF", PDF, "pdf"},
 		{"StringPNG", PNG, "png"},
 		{"StringSVG", SVG, "svg"},
@@ -153,10 +272,16 @@ func TestImageFormat_UnmarshalText(t *testing.T) {
 		{"UnmarshalTextHEIFwoDot", args{[]byte("heif")}, HEIF, false},
 		{"UnmarshalTextICOwDot", args{[]byte(".ico")}, ICO, false},
 		{"UnmarshalTextICOwoDot", args{[]byte("ico")}, ICO, false},
+		{"UnmarshalTextJP2wDot", args{[]byte(".jp2")}, JP2K, false},
+		{"UnmarshalTextJP2woDot", args{[]byte("jp2")}, JP2K, false},
+		{"UnmarshalTextJ2KwDot", args{[]byte(".j2k")}, JP2K, false},
+		{"UnmarshalTextJ2KwoDot", args{[]byte("j2k")}, JP2K, false},
 		{"UnmarshalTextJPGwDot", args{[]byte(".jpg")}, JPEG, false},
 		{"UnmarshalTextJPGwoDot", args{[]byte("jpg")}, JPEG, false},
 		{"UnmarshalTextJPEGwDot", args{[]byte(".jpeg")}, JPEG, false},
 		{"UnmarshalTextJPEGwoDot", args{[]byte("jpeg")}, JPEG, false},
+		{"UnmarshalTextJXLwDot", args{[]byte(".jxl")}, JXL, false},
+		{"UnmarshalTextJXLwoDot", args{[]byte("jxl")}, JXL, false},
 		{"UnmarshalTextPDFwDot", args{[]byte(".pdf")}, PDF, false},
 		{"UnmarshalTextPDFwoDot", args{[]byte("pdf")}, PDF, false},
 		{"UnmarshalTextPNGwDot", args{[]byte(".png")}, PNG, false},
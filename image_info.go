@@ -0,0 +1,206 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"bytes"
+	"unsafe"
+)
+
+type BandFormat int
+type Interpretation int
+
+const (
+	BandFormatNotSet    = BandFormat(C.VIPS_FORMAT_NOTSET)
+	BandFormatUChar     = BandFormat(C.VIPS_FORMAT_UCHAR)
+	BandFormatChar      = BandFormat(C.VIPS_FORMAT_CHAR)
+	BandFormatUShort    = BandFormat(C.VIPS_FORMAT_USHORT)
+	BandFormatShort     = BandFormat(C.VIPS_FORMAT_SHORT)
+	BandFormatUInt      = BandFormat(C.VIPS_FORMAT_UINT)
+	BandFormatInt       = BandFormat(C.VIPS_FORMAT_INT)
+	BandFormatFloat     = BandFormat(C.VIPS_FORMAT_FLOAT)
+	BandFormatComplex   = BandFormat(C.VIPS_FORMAT_COMPLEX)
+	BandFormatDouble    = BandFormat(C.VIPS_FORMAT_DOUBLE)
+	BandFormatDPComplex = BandFormat(C.VIPS_FORMAT_DPCOMPLEX)
+
+	InterpretationError     = Interpretation(C.VIPS_INTERPRETATION_ERROR)
+	InterpretationMultiband = Interpretation(C.VIPS_INTERPRETATION_MULTIBAND)
+	InterpretationBW        = Interpretation(C.VIPS_INTERPRETATION_B_W)
+	InterpretationHistogram = Interpretation(C.VIPS_INTERPRETATION_HISTOGRAM)
+	InterpretationXYZ       = Interpretation(C.VIPS_INTERPRETATION_XYZ)
+	InterpretationLab       = Interpretation(C.VIPS_INTERPRETATION_LAB)
+	InterpretationCMYK      = Interpretation(C.VIPS_INTERPRETATION_CMYK)
+	InterpretationLabQ      = Interpretation(C.VIPS_INTERPRETATION_LABQ)
+	InterpretationRGB       = Interpretation(C.VIPS_INTERPRETATION_RGB)
+	InterpretationCMC       = Interpretation(C.VIPS_INTERPRETATION_CMC)
+	InterpretationLCh       = Interpretation(C.VIPS_INTERPRETATION_LCH)
+	InterpretationLabS      = Interpretation(C.VIPS_INTERPRETATION_LABS)
+	InterpretationSRGB      = Interpretation(C.VIPS_INTERPRETATION_sRGB)
+	InterpretationYXY       = Interpretation(C.VIPS_INTERPRETATION_YXY)
+	InterpretationFourier   = Interpretation(C.VIPS_INTERPRETATION_FOURIER)
+	InterpretationRGB16     = Interpretation(C.VIPS_INTERPRETATION_RGB16)
+	InterpretationGrey16    = Interpretation(C.VIPS_INTERPRETATION_GREY16)
+	InterpretationMatrix    = Interpretation(C.VIPS_INTERPRETATION_MATRIX)
+	InterpretationScRGB     = Interpretation(C.VIPS_INTERPRETATION_scRGB)
+	InterpretationHSV       = Interpretation(C.VIPS_INTERPRETATION_HSV)
+)
+
+type ImageInfo struct {
+	Format         ImageFormat
+	Width          int
+	Height         int
+	Bands          int
+	BandFormat     BandFormat
+	Interpretation Interpretation
+	// Pages is the number of pages or animation frames in the file. Width and Height describe the first page
+	Pages         int
+	PageHeight    int
+	Animated      bool
+	Orientation   int
+	HasICCProfile bool
+}
+
+// Probe reads the image header only. Loaders decode pixels lazily, so nothing is decoded here
+// and it is cheap enough to validate uploads before any processing.
+func Probe(buf []byte) (ImageInfo, error) {
+	// The formats decoded in Go are described from their headers, they have no lazy loader
+	switch imgType := FormatByMagicNumber(buf); {
+	case imgType == ICO:
+		return probeIco(buf)
+	case imgType == BMP && !operationAvailable("magickload_buffer"):
+		return probeBMP(buf)
+	}
+
+	img, err := Load(buf)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	defer img.Clear()
+
+	return ImageInfo{
+		Format:         FormatByMagicNumber(buf),
+		Width:          img.Width(),
+		Height:         img.Height(),
+		Bands:          img.Bands(),
+		BandFormat:     img.BandFormat(),
+		Interpretation: img.Interpretation(),
+		Pages:          img.Pages(),
+		PageHeight:     img.PageHeight(),
+		Animated:       img.Pages() > 1 && (img.hasField("delay") || img.hasField("gif-delay")),
+		Orientation:    img.Orientation(),
+		HasICCProfile:  img.hasField("icc-profile-data"),
+	}, nil
+}
+
+// probeIco describes the entry LoadIco picks for size 0
+func probeIco(buf []byte) (ImageInfo, error) {
+	entries, err := parseIcoDir(buf)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+
+	entry, err := pickIcoEntry(entries, 0)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+
+	if bytes.HasPrefix(entry.data, pngMagic) {
+		info, err := Probe(entry.data)
+		info.Format = ICO
+
+		return info, err
+	}
+
+	w, h, err := icoDIBSize(entry.data)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+
+	return rgbaInfo(ICO, w, h), nil
+}
+
+func probeBMP(buf []byte) (ImageInfo, error) {
+	hdr, err := parseBMPHeader(buf)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+
+	return rgbaInfo(BMP, hdr.w, hdr.h), nil
+}
+
+// rgbaInfo describes the 8-bit RGBA image the Go decoders produce
+func rgbaInfo(format ImageFormat, w, h int) ImageInfo {
+	return ImageInfo{
+		Format:         format,
+		Width:          w,
+		Height:         h,
+		Bands:          4,
+		BandFormat:     BandFormatUChar,
+		Interpretation: InterpretationSRGB,
+		Pages:          1,
+		PageHeight:     h,
+		Orientation:    1,
+	}
+}
+
+func (img *VipsImage) Bands() int {
+	return int(img.img.Bands)
+}
+
+func (img *VipsImage) BandFormat() BandFormat {
+	return BandFormat(C.vips_band_format_go(img.img))
+}
+
+func (img *VipsImage) Interpretation() Interpretation {
+	return Interpretation(C.vips_image_get_interpretation(img.img))
+}
+
+// Pages returns the number of pages in the source file, 1 for single-page formats
+func (img *VipsImage) Pages() int {
+	return int(C.vips_image_get_n_pages(img.img))
+}
+
+func (img *VipsImage) PageHeight() int {
+	return int(C.vips_image_get_page_height(img.img))
+}
+
+// Orientation returns the EXIF orientation 1-8, 1 if the image has no orientation tag
+func (img *VipsImage) Orientation() int {
+	return int(C.vips_get_orientation(img.img))
+}
+
+func (img *VipsImage) hasField(name string) bool {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	return C.vips_image_get_typeof(img.img, cName) != 0
+}
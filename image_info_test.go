@@ -0,0 +1,114 @@
+package libvips_go
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestProbe(t *testing.T) {
+	startVips(t)
+
+	a4 := readFixture(t, "wiki_a4.png")
+
+	tests := []struct {
+		name    string
+		buf     []byte
+		want    ImageInfo
+		wantErr bool
+	}{
+		{
+			name: "PNG",
+			buf:  readFixture(t, "blank.png"),
+			want: ImageInfo{Format: PNG, Width: 3, Height: 2},
+		},
+		{
+			name: "JPEG",
+			buf:  readFixture(t, "blank.jpeg"),
+			want: ImageInfo{Format: JPEG, Width: 1, Height: 1},
+		},
+		{
+			name: "GIF",
+			buf:  readFixture(t, "blank.gif"),
+			want: ImageInfo{Format: GIF, Width: 1, Height: 1},
+		},
+		{
+			// Only the header is read, the missing pixel data isn't noticed
+			name: "Truncated PNG",
+			buf:  a4[:len(a4)/2],
+			want: ImageInfo{Format: PNG, Width: 1200, Height: 1800},
+		},
+		{
+			name: "ICO",
+			buf:  readFixture(t, "blank.ico"),
+			want: ImageInfo{Format: ICO, Width: 1, Height: 1},
+		},
+		{
+			name: "PNG ICO",
+			buf:  wrapIco(readFixture(t, "blank.png"), 3, 2),
+			want: ImageInfo{Format: ICO, Width: 3, Height: 2},
+		},
+		{
+			name: "BMP",
+			buf:  readFixture(t, "blank.bmp"),
+			want: ImageInfo{Format: BMP, Width: 1, Height: 1},
+		},
+		{
+			name:    "Unknown",
+			buf:     []byte("not an image"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Probe(tt.buf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Probe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got.Format != tt.want.Format || got.Width != tt.want.Width || got.Height != tt.want.Height {
+				t.Errorf("Probe() = %s %dx%d, want %s %dx%d", got.Format, got.Width, got.Height,
+					tt.want.Format, tt.want.Width, tt.want.Height)
+			}
+
+			if !tt.wantErr && (got.Pages != 1 || got.Animated || got.Orientation != 1) {
+				t.Errorf("Probe() pages = %d, animated = %v, orientation = %d, want 1, false, 1",
+					got.Pages, got.Animated, got.Orientation)
+			}
+		})
+	}
+}
+
+func TestProbe_GoDecoded(t *testing.T) {
+	// Neither has the pixel data, only the headers are read
+	bmp := testBMP(8000, 6000, 24, bmpRGB, nil, nil)
+
+	dib := make([]byte, dibHeaderSize)
+	binary.LittleEndian.PutUint32(dib[0:], dibHeaderSize)
+	binary.LittleEndian.PutUint32(dib[4:], 48)
+	binary.LittleEndian.PutUint32(dib[8:], 2*32)
+
+	ico := wrapIco(dib, 48, 32)
+
+	tests := []struct {
+		name  string
+		probe func([]byte) (ImageInfo, error)
+		buf   []byte
+		want  ImageInfo
+	}{
+		{"BMP", probeBMP, bmp, rgbaInfo(BMP, 8000, 6000)},
+		{"ICO", probeIco, ico, rgbaInfo(ICO, 48, 32)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.probe(tt.buf)
+			if err != nil {
+				t.Fatalf("probe() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("probe() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
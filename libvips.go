@@ -41,9 +41,8 @@ import (
 	"image"
 	"image/color"
 	"math"
-	"os"
 	"runtime"
-	"strconv"
+	"sync"
 	"unsafe"
 )
 
@@ -52,73 +51,53 @@ const Version = string(C.VIPS_VERSION)
 var (
 	ErrUnsupportedImageFormat = fmt.Errorf("unsupported image file format")
 	ErrInvalidFileFormat      = fmt.Errorf("invalid file format")
+	ErrAlreadyStarted         = fmt.Errorf("vips library is already started")
 )
 
-func init() {
-	runtime.LockOSThread()
-	defer runtime.UnlockOSThread()
+var (
+	startupMu sync.Mutex
+	started   bool
+)
 
-	if err := C.vips_initialize_go(); err != 0 {
-		Shutdown()
-		fmt.Println("failed to initialize vips library:", err)
-		os.Exit(1)
+// Startup initializes libvips with cfg. It should be called once, before any image is loaded.
+func Startup(cfg Config) error {
+	if err := cfg.validate(); err != nil {
+		return fmt.Errorf("failed to initialize vips library: %w", err)
 	}
 
-	// Disable libvips cache. Since processing pipeline is fine tuned, we won't get much profit from it.
-	// Enabled cache can cause SIGSEGV on Musl-based systems like Alpine.
-
-	var cacheMaxMem uint64
-	var cacheMax int
-	var vectorEnabled, leakCheck bool
-	var err error
+	startupMu.Lock()
+	defer startupMu.Unlock()
 
-	cacheMaxMemVal := os.Getenv("VIPS_CACHE_MAX_MEM")
-	if cacheMaxMemVal != "" {
-		if cacheMaxMem, err = strconv.ParseUint(cacheMaxMemVal, 10, 64); err != nil {
-			fmt.Printf("failed to initialize vips library: invalid VIPS_CACHE_MAX_MEM value \"%s\": %s\n", cacheMaxMemVal, err)
-			os.Exit(1)
-		}
+	if started {
+		return ErrAlreadyStarted
 	}
 
-	cacheMaxVal := os.Getenv("VIPS_CACHE_MAX")
-	if cacheMaxVal != "" {
-		if cacheMax, err = strconv.Atoi(cacheMaxVal); err != nil {
-			fmt.Printf("failed to initialize vips library: invalid VIPS_CACHE_MAX value \"%s\": %s\n", cacheMaxVal, err)
-			os.Exit(1)
-		}
-	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
 
-	if len(os.Getenv("VIPS_CACHE_TRACE")) > 0 {
-		C.vips_cache_set_trace(C.gboolean(1))
+	if C.vips_initialize_go() != 0 {
+		err := vipsError("Startup")
+		Shutdown()
+		return fmt.Errorf("failed to initialize vips library: %w", err)
 	}
 
-	C.vips_cache_set_max_mem(C.size_t(cacheMaxMem))
-	C.vips_cache_set_max(C.int(cacheMax))
+	// libvips can't be initialized again after shutdown, so it's left running and the next Startup
+	// may apply a corrected config
+	if err := cfg.apply(); err != nil {
+		return fmt.Errorf("failed to initialize vips library: %w", err)
+	}
 
-	C.vips_concurrency_set(1)
+	started = true
 
-	vectorEnabledVal := os.Getenv("VIPS_VECTOR_ENABLED")
-	if vectorEnabledVal != "" {
-		vectorEnabled, err = strconv.ParseBool(vectorEnabledVal)
-		if err != nil {
-			fmt.Printf("failed to initialize vips library: invalid VIPS_VECTOR_ENABLED value \"%s\": %s\n", vectorEnabledVal, err)
-			os.Exit(1)
-		}
-	}
-	// Vector calculations cause SIGSEGV sometimes when working with JPEG.
-	// It's better to disable it since profit it quite small
-	C.vips_vector_set_enabled(gbool(vectorEnabled))
+	return nil
+}
 
-	leakCheckVal := os.Getenv("VIPS_LEAK_CHECK")
-	if leakCheckVal != "" {
-		leakCheck, err = strconv.ParseBool(vectorEnabledVal)
-		if err != nil {
-			fmt.Printf("failed to initialize vips library: invalid VIPS_LEAK_CHECK value \"%s\": %s\n", leakCheckVal, err)
-			os.Exit(1)
-		}
-	}
+// operationAvailable reports whether the linked libvips has the operation, e.g. "magickload_buffer"
+func operationAvailable(name string) bool {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
 
-	C.vips_leak_set(gbool(leakCheck))
+	return C.vips_operation_available_go(cName) != 0
 }
 
 type VipsImage struct{ img *C.VipsImage }
@@ -148,7 +127,7 @@ func (img *VipsImage) Clear() {
 func (img *VipsImage) CopyMemory() error {
 	var tmp *C.VipsImage
 	if tmp = C.vips_image_copy_memory(img.img); tmp == nil {
-		return vipsError()
+		return vipsError("CopyMemory")
 	}
 
 	C.swap_and_clear_go(&img.img, tmp)
@@ -183,7 +162,15 @@ func (img *VipsImage) Save(imgType ImageFormat, opts encodeConfig) ([]byte, erro
 		err = C.vips_avifsave_go(img.img, &ptr, &imgSize, opts.quality)
 	case HEIF:
 		err = C.vips_heifsave_go(img.img, &ptr, &imgSize, opts.quality, opts.heifCompression, opts.lossless)
+	case JXL:
+		err = jxlOptions(opts).save(img.img, nil, &ptr, &imgSize)
+	case JP2K:
+		err = jp2kOptions(opts).save(img.img, nil, &ptr, &imgSize)
 	case BMP:
+		if !operationAvailable("magicksave_buffer") {
+			return img.saveBMP()
+		}
+
 		err = C.vips_bmpsave_go(img.img, &ptr, &imgSize)
 	case PDF:
 		err = C.vips_pdfsave_go(img.img, &ptr, &imgSize)
@@ -191,7 +178,7 @@ func (img *VipsImage) Save(imgType ImageFormat, opts encodeConfig) ([]byte, erro
 		return nil, ErrUnsupportedImageFormat
 	}
 	if err != 0 {
-		return nil, vipsError()
+		return nil, vipsError("Save")
 	}
 
 	return C.GoBytes(ptr, C.int(imgSize)), nil
@@ -208,7 +195,7 @@ func (img *VipsImage) SaveAsIco() ([]byte, error) {
 	defer C.g_free_go(&ptr)
 
 	if C.vips_pngsave_go(img.img, &ptr, &imgSize, 0, 0, 256, 1) != 0 {
-		return nil, vipsError()
+		return nil, vipsError("SaveAsIco")
 	}
 
 	buf := new(bytes.Buffer)
@@ -269,11 +256,11 @@ func (img *VipsImage) Resize(scale float64, hasAlpha bool) error {
 
 	if hasAlpha {
 		if C.vips_resize_with_premultiply_go(img.img, &tmp, C.double(scale)) != 0 {
-			return vipsError()
+			return vipsError("Resize")
 		}
 	} else {
 		if C.vips_resize_go(img.img, &tmp, C.double(scale)) != 0 {
-			return vipsError()
+			return vipsError("Resize")
 		}
 	}
 
@@ -288,7 +275,7 @@ func (img *VipsImage) Rotate(angle int) error {
 	vipsAngle := (angle / 90) % 4
 
 	if C.vips_rotate_go(img.img, &tmp, C.VipsAngle(vipsAngle)) != 0 {
-		return vipsError()
+		return vipsError("Rotate")
 	}
 
 	C.vips_autorot_remove_angle(tmp)
@@ -300,7 +287,7 @@ func (img *VipsImage) Rotate(angle int) error {
 func (img *VipsImage) Flip() error {
 	var tmp *C.VipsImage
 	if C.vips_flip_horizontal_go(img.img, &tmp) != 0 {
-		return vipsError()
+		return vipsError("Flip")
 	}
 
 	C.swap_and_clear_go(&img.img, tmp)
@@ -311,7 +298,7 @@ func (img *VipsImage) Flip() error {
 func (img *VipsImage) EnsureAlpha() error {
 	var tmp *C.VipsImage
 	if C.vips_ensure_alpha_go(img.img, &tmp) != 0 {
-		return vipsError()
+		return vipsError("EnsureAlpha")
 	}
 
 	C.swap_and_clear_go(&img.img, tmp)
@@ -322,7 +309,7 @@ func (img *VipsImage) EnsureAlpha() error {
 func (img *VipsImage) Blur(sigma float32) error {
 	var tmp *C.VipsImage
 	if C.vips_gaussblur_go(img.img, &tmp, C.double(sigma)) != 0 {
-		return vipsError()
+		return vipsError("Blur")
 	}
 
 	C.swap_and_clear_go(&img.img, tmp)
@@ -333,7 +320,7 @@ func (img *VipsImage) Blur(sigma float32) error {
 func (img *VipsImage) Sharpen(sigma float32) error {
 	var tmp *C.VipsImage
 	if C.vips_sharpen_go(img.img, &tmp, C.double(sigma)) != 0 {
-		return vipsError()
+		return vipsError("Sharpen")
 	}
 
 	C.swap_and_clear_go(&img.img, tmp)
@@ -351,7 +338,7 @@ func (img *VipsImage) Trim(threshold float64, smart bool, color color.RGBA, equa
 	if C.vips_trim_go(img.img, &tmp, C.double(threshold),
 		gbool(smart), C.double(color.R), C.double(color.G), C.double(color.B),
 		gbool(equalHor), gbool(equalVer)) != 0 {
-		return vipsError()
+		return vipsError("Trim")
 	}
 
 	C.swap_and_clear_go(&img.img, tmp)
@@ -361,7 +348,7 @@ func (img *VipsImage) Trim(threshold float64, smart bool, color color.RGBA, equa
 
 func (img *VipsImage) Extract(out *VipsImage, pt image.Point, w, h int) error {
 	if C.vips_extract_area_go(img.img, &out.img, C.int(pt.X), C.int(pt.Y), C.int(w), C.int(h)) != 0 {
-		return vipsError()
+		return vipsError("Extract")
 	}
 
 	return nil
@@ -430,7 +417,7 @@ func (img *VipsImage) fillCrop(dstW, dstH int, pt image.Point) error {
 func (img *VipsImage) AddWatermark(wm *VipsImage, pt image.Point, opacity float64) error {
 	var tmp *C.VipsImage
 	if err := C.vips_apply_watermark_go(img.img, wm.img, &tmp, C.int(pt.X), C.int(pt.Y), C.float(opacity)); err != 0 {
-		return vipsError()
+		return vipsError("AddWatermark")
 	}
 
 	C.swap_and_clear_go(&img.img, tmp)
@@ -442,7 +429,7 @@ func (img *VipsImage) AddWatermark(wm *VipsImage, pt image.Point, opacity float6
 func (img *VipsImage) Strip() error {
 	var tmp *C.VipsImage
 	if C.vips_strip_go(img.img, &tmp) != 0 {
-		return vipsError()
+		return vipsError("Strip")
 	}
 
 	C.swap_and_clear_go(&img.img, tmp)
@@ -455,7 +442,7 @@ func (img *VipsImage) Strip() error {
 func (img *VipsImage) SmartCrop(w, h int) error {
 	var tmp *C.VipsImage
 	if C.vips_smartcrop_go(img.img, &tmp, C.int(w), C.int(h)) != 0 {
-		return vipsError()
+		return vipsError("SmartCrop")
 	}
 
 	C.swap_and_clear_go(&img.img, tmp)
@@ -466,7 +453,7 @@ func (img *VipsImage) SmartCrop(w, h int) error {
 func (img *VipsImage) Crop(dstW, dstH int, pt image.Point) error {
 	var tmp *C.VipsImage
 	if C.vips_extract_area_go(img.img, &tmp, C.int(pt.X), C.int(pt.Y), C.int(dstW), C.int(dstH)) != 0 {
-		return vipsError()
+		return vipsError("Crop")
 	}
 
 	C.swap_and_clear_go(&img.img, tmp)
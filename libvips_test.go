@@ -0,0 +1,59 @@
+package libvips_go
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+var (
+	startupOnce sync.Once
+	startupErr  error
+)
+
+// startVips starts libvips once for the tests which decode or encode images. They are skipped when the linked
+// library can't do it, e.g. it's built without the png loader.
+func startVips(t *testing.T) {
+	t.Helper()
+
+	startupOnce.Do(func() {
+		if startupErr = Startup(DefaultConfig); startupErr != nil {
+			return
+		}
+
+		if !operationAvailable("pngload_buffer") {
+			startupErr = fmt.Errorf("the png loader is not available")
+		}
+	})
+
+	if startupErr != nil {
+		t.Skipf("libvips is not usable: %s", startupErr)
+	}
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	buf, err := os.ReadFile(filepath.Join(".test", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf
+}
+
+func loadFixture(t *testing.T, name string) *VipsImage {
+	t.Helper()
+
+	startVips(t)
+
+	img, err := Load(readFixture(t, name))
+	if err != nil {
+		t.Fatalf("Load(%s) error = %v", name, err)
+	}
+	t.Cleanup(img.Clear)
+
+	return img
+}
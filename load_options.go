@@ -0,0 +1,236 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import "fmt"
+
+type FailOn int
+
+// Mirrors VipsFailOn, which is only available since libvips 8.12
+const (
+	FailOnNone FailOn = iota
+	FailOnTruncated
+	FailOnError
+	FailOnWarning
+)
+
+var ErrImageTooLarge = fmt.Errorf("image is too large")
+
+// ImageTooLargeError reports which of the LoadOptions limits the image exceeds. It matches ErrImageTooLarge
+// with errors.Is.
+type ImageTooLargeError struct {
+	Limit string
+	Value int
+	Max   int
+}
+
+func (e *ImageTooLargeError) Error() string {
+	return fmt.Sprintf("image is too large: %s %d exceeds the limit of %d", e.Limit, e.Value, e.Max)
+}
+
+func (e *ImageTooLargeError) Is(target error) bool {
+	return target == ErrImageTooLarge
+}
+
+// LoadOptions protects the loader against decompression bombs. Zero limits are not checked.
+// Limits are checked against the image header, before any pixel is decoded. They are taken by
+// LoadWithOptions, LoadReaderWithOptions, LoadSourceWithOptions, LoadFromFileWithOptions,
+// LoadPDFPagesWithOptions and ThumbnailOptions.Load, the loaders without options don't limit the input.
+type LoadOptions struct {
+	MaxInputBytes int
+	MaxPixels     int
+	MaxWidth      int
+	MaxHeight     int
+	// MaxPages limits the page count of documents like PDF or TIFF
+	MaxPages int
+	// MaxFrames limits the frame count of animations like GIF or WebP
+	MaxFrames int
+
+	// FailOn sets the level of loader warnings and errors that abort the load
+	FailOn FailOn
+	// Unlimited removes the libvips own safety limits of the SVG, PNG and HEIF loaders
+	Unlimited bool
+
+	// DPI and Scale set the rasterisation of SVG and PDF, zero values keep the libvips defaults of 72 and 1
+	DPI   float64
+	Scale float64
+	// Width and Height fit SVG and PDF into the box keeping the aspect ratio, the scale is computed
+	// from the document size. They take precedence over Scale.
+	Width  int
+	Height int
+
+	// AutoRotate applies the EXIF orientation after load
+	AutoRotate bool
+}
+
+func (opts LoadOptions) params() C.LoadParams {
+	params := C.LoadParams{
+		fail_on:   C.int(opts.FailOn),
+		unlimited: gbool(opts.Unlimited),
+		dpi:       72,
+		scale:     1,
+	}
+
+	if opts.DPI > 0 {
+		params.dpi = C.double(opts.DPI)
+	}
+
+	if opts.Scale > 0 {
+		params.scale = C.double(opts.Scale)
+	}
+
+	return params
+}
+
+func (opts LoadOptions) validate() error {
+	if opts.FailOn < FailOnNone || opts.FailOn > FailOnWarning {
+		return fmt.Errorf("invalid fail on value %d", opts.FailOn)
+	}
+
+	if opts.DPI < 0 {
+		return fmt.Errorf("invalid dpi value %g", opts.DPI)
+	}
+
+	if opts.Scale < 0 {
+		return fmt.Errorf("invalid scale value %g", opts.Scale)
+	}
+
+	if opts.Width < 0 {
+		return fmt.Errorf("invalid width value %d", opts.Width)
+	}
+
+	if opts.Height < 0 {
+		return fmt.Errorf("invalid height value %d", opts.Height)
+	}
+
+	return nil
+}
+
+// fitScale returns the scale which fits the document of w x h, rendered at scale 1, into Width and Height
+func (opts LoadOptions) fitScale(w, h int) float64 {
+	if w <= 0 || h <= 0 {
+		return 1
+	}
+
+	scale := 0.0
+
+	if opts.Width > 0 {
+		scale = float64(opts.Width) / float64(w)
+	}
+
+	if opts.Height > 0 {
+		if s := float64(opts.Height) / float64(h); scale == 0 || s < scale {
+			scale = s
+		}
+	}
+
+	if scale == 0 {
+		return 1
+	}
+
+	return scale
+}
+
+func (opts LoadOptions) checkInput(buf []byte) error {
+	if opts.MaxInputBytes > 0 && len(buf) > opts.MaxInputBytes {
+		return &ImageTooLargeError{"input bytes", len(buf), opts.MaxInputBytes}
+	}
+
+	return nil
+}
+
+// limits returns the options which only accept or reject the image without changing it
+func (opts LoadOptions) limits() LoadOptions {
+	return LoadOptions{
+		MaxInputBytes: opts.MaxInputBytes,
+		MaxPixels:     opts.MaxPixels,
+		MaxWidth:      opts.MaxWidth,
+		MaxHeight:     opts.MaxHeight,
+		MaxPages:      opts.MaxPages,
+		MaxFrames:     opts.MaxFrames,
+		FailOn:        opts.FailOn,
+	}
+}
+
+// finish checks the header of the loaded image and applies the orientation. The image is cleared on error.
+func (opts LoadOptions) finish(img *VipsImage) (*VipsImage, error) {
+	if err := opts.checkHeader(img); err != nil {
+		img.Clear()
+		return nil, err
+	}
+
+	if opts.AutoRotate {
+		if err := img.AutoRotate(); err != nil {
+			img.Clear()
+			return nil, err
+		}
+	}
+
+	return img, nil
+}
+
+func (opts LoadOptions) checkHeader(img *VipsImage) error {
+	if err := opts.checkSize(img.Width(), img.Height()); err != nil {
+		return err
+	}
+
+	return opts.checkPages(img.Pages(), img.hasField("delay") || img.hasField("gif-delay"))
+}
+
+func (opts LoadOptions) checkSize(w, h int) error {
+	if opts.MaxWidth > 0 && w > opts.MaxWidth {
+		return &ImageTooLargeError{"width", w, opts.MaxWidth}
+	}
+
+	if opts.MaxHeight > 0 && h > opts.MaxHeight {
+		return &ImageTooLargeError{"height", h, opts.MaxHeight}
+	}
+
+	if opts.MaxPixels > 0 && w*h > opts.MaxPixels {
+		return &ImageTooLargeError{"pixels", w * h, opts.MaxPixels}
+	}
+
+	return nil
+}
+
+// checkPages limits the frames of animations and the pages of the other formats
+func (opts LoadOptions) checkPages(pages int, animated bool) error {
+	if animated {
+		if opts.MaxFrames > 0 && pages > opts.MaxFrames {
+			return &ImageTooLargeError{"frames", pages, opts.MaxFrames}
+		}
+	} else if opts.MaxPages > 0 && pages > opts.MaxPages {
+		return &ImageTooLargeError{"pages", pages, opts.MaxPages}
+	}
+
+	return nil
+}
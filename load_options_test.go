@@ -0,0 +1,208 @@
+package libvips_go
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadOptions_checkInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    LoadOptions
+		size    int
+		wantErr bool
+	}{
+		{"NoLimit", LoadOptions{}, 1 << 20, false},
+		{"UnderLimit", LoadOptions{MaxInputBytes: 100}, 99, false},
+		{"AtLimit", LoadOptions{MaxInputBytes: 100}, 100, false},
+		{"OverLimit", LoadOptions{MaxInputBytes: 100}, 101, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.checkInput(make([]byte, tt.size))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrImageTooLarge) {
+				t.Errorf("checkInput() error = %v, want ErrImageTooLarge", err)
+			}
+		})
+	}
+}
+
+func TestLoadOptions_checkSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      LoadOptions
+		w, h      int
+		wantLimit string
+	}{
+		{"NoLimit", LoadOptions{}, 1 << 16, 1 << 16, ""},
+		{"UnderWidth", LoadOptions{MaxWidth: 100}, 99, 1000, ""},
+		{"AtWidth", LoadOptions{MaxWidth: 100}, 100, 1000, ""},
+		{"OverWidth", LoadOptions{MaxWidth: 100}, 101, 1, "width"},
+		{"AtHeight", LoadOptions{MaxHeight: 100}, 1000, 100, ""},
+		{"OverHeight", LoadOptions{MaxHeight: 100}, 1, 101, "height"},
+		{"AtPixels", LoadOptions{MaxPixels: 100}, 10, 10, ""},
+		{"OverPixels", LoadOptions{MaxPixels: 100}, 11, 10, "pixels"},
+		{"WidthBeforePixels", LoadOptions{MaxWidth: 10, MaxPixels: 100}, 11, 11, "width"},
+		{"PixelsWithinSides", LoadOptions{MaxWidth: 100, MaxHeight: 100, MaxPixels: 100}, 50, 50, "pixels"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.checkSize(tt.w, tt.h)
+
+			var tooLarge *ImageTooLargeError
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Errorf("checkSize() error = %v, want nil", err)
+				}
+			} else if !errors.As(err, &tooLarge) || tooLarge.Limit != tt.wantLimit {
+				t.Errorf("checkSize() error = %v, want %s limit", err, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestLoadOptions_checkPages(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      LoadOptions
+		pages     int
+		animated  bool
+		wantLimit string
+	}{
+		{"NoLimit", LoadOptions{}, 1000, false, ""},
+		{"AtPages", LoadOptions{MaxPages: 10}, 10, false, ""},
+		{"OverPages", LoadOptions{MaxPages: 10}, 11, false, "pages"},
+		{"FramesNotPages", LoadOptions{MaxPages: 10}, 11, true, ""},
+		{"AtFrames", LoadOptions{MaxFrames: 10}, 10, true, ""},
+		{"OverFrames", LoadOptions{MaxFrames: 10}, 11, true, "frames"},
+		{"PagesNotFrames", LoadOptions{MaxFrames: 10}, 11, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.checkPages(tt.pages, tt.animated)
+
+			var tooLarge *ImageTooLargeError
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Errorf("checkPages() error = %v, want nil", err)
+				}
+			} else if !errors.As(err, &tooLarge) || tooLarge.Limit != tt.wantLimit {
+				t.Errorf("checkPages() error = %v, want %s limit", err, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestLoadWithOptions_Limits(t *testing.T) {
+	startVips(t)
+
+	a4 := readFixture(t, "wiki_a4.png")
+
+	tests := []struct {
+		name    string
+		opts    LoadOptions
+		wantErr bool
+	}{
+		{"NoLimit", LoadOptions{}, false},
+		{"Fits", LoadOptions{MaxWidth: 1200, MaxHeight: 1800, MaxPixels: 1200 * 1800}, false},
+		{"Width", LoadOptions{MaxWidth: 1199}, true},
+		{"Height", LoadOptions{MaxHeight: 1799}, true},
+		{"Pixels", LoadOptions{MaxPixels: 1 << 20}, true},
+		{"InputBytes", LoadOptions{MaxInputBytes: 1024}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaders := map[string]func() (*VipsImage, error){
+				"LoadWithOptions":       func() (*VipsImage, error) { return LoadWithOptions(a4, tt.opts) },
+				"LoadReaderWithOptions": func() (*VipsImage, error) { return LoadReaderWithOptions(bytes.NewReader(a4), tt.opts) },
+				"LoadFromFileWithOptions": func() (*VipsImage, error) {
+					return LoadFromFileWithOptions(filepath.Join(".test", "wiki_a4.png"), tt.opts)
+				},
+				"Thumbnail": func() (*VipsImage, error) {
+					return Thumbnail(a4, 100, 100, ThumbnailOptions{Load: tt.opts})
+				},
+			}
+			for name, load := range loaders {
+				img, err := load()
+				if err == nil {
+					// The input limit of a reader is checked while the pixels are read
+					_, err = img.Save(PNG, DefaultEncodeConfig)
+					img.Clear()
+				}
+
+				if (err != nil) != tt.wantErr {
+					t.Errorf("%s() error = %v, wantErr %v", name, err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestImageTooLargeError_Is(t *testing.T) {
+	var err error = &ImageTooLargeError{"pixels", 200, 100}
+
+	if !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("errors.Is(%v, ErrImageTooLarge) = false, want true", err)
+	}
+
+	if errors.Is(err, ErrUnsupportedImageFormat) {
+		t.Errorf("errors.Is(%v, ErrUnsupportedImageFormat) = true, want false", err)
+	}
+
+	var tooLarge *ImageTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != "pixels" {
+		t.Errorf("errors.As(%v) = %v, want pixels limit", err, tooLarge)
+	}
+}
+
+func TestLoadOptions_fitScale(t *testing.T) {
+	tests := []struct {
+		name string
+		opts LoadOptions
+		w, h int
+		want float64
+	}{
+		{"NoBox", LoadOptions{}, 100, 50, 1},
+		{"Width", LoadOptions{Width: 400}, 100, 50, 4},
+		{"Height", LoadOptions{Height: 25}, 100, 50, 0.5},
+		{"BoxLimitedByHeight", LoadOptions{Width: 400, Height: 100}, 100, 50, 2},
+		{"BoxLimitedByWidth", LoadOptions{Width: 150, Height: 500}, 100, 50, 1.5},
+		{"EmptyDocument", LoadOptions{Width: 400}, 0, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.fitScale(tt.w, tt.h); got != tt.want {
+				t.Errorf("fitScale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadOptions_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    LoadOptions
+		wantErr bool
+	}{
+		{"Zero", LoadOptions{}, false},
+		{"Vector", LoadOptions{DPI: 300, Scale: 2, Width: 100, Height: 100}, false},
+		{"NegativeDPI", LoadOptions{DPI: -1}, true},
+		{"NegativeScale", LoadOptions{Scale: -0.5}, true},
+		{"NegativeWidth", LoadOptions{Width: -1}, true},
+		{"NegativeHeight", LoadOptions{Height: -1}, true},
+		{"FailOn", LoadOptions{FailOn: FailOnWarning}, false},
+		{"InvalidFailOn", LoadOptions{FailOn: FailOn(42)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
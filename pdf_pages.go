@@ -0,0 +1,159 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"fmt"
+	"image/color"
+	"unsafe"
+)
+
+type PDFDocumentInfo struct {
+	Pages int
+	// PageSizes are measured in points, like the page sizes of SavePDF, and rounded to 1/100 of a point
+	PageSizes []PageSize
+}
+
+// PDFPageOptions zero DPI and Scale are the libvips defaults of 72 and 1, like the ones of LoadOptions
+type PDFPageOptions struct {
+	// DPI is the render resolution, 72 renders a pixel per point
+	DPI   float64
+	Scale float64
+	// Background fills the transparent areas, white if it's nil
+	Background color.Color
+	// Password of an encrypted document, requires libvips 8.14+
+	Password string
+}
+
+var DefaultPDFPageOptions = PDFPageOptions{
+	DPI:   72,
+	Scale: 1,
+}
+
+// pdfInfoScale keeps the page sizes of PDFInfo to 1/100 of a point, libvips rounds the page to whole pixels
+const pdfInfoScale = 100
+
+// PDFInfo returns the number of pages and their sizes. The pages aren't rendered, but libvips only
+// reports the size of the loaded page, so the header of every page is loaded separately.
+func PDFInfo(buf []byte) (PDFDocumentInfo, error) {
+	opts := PDFPageOptions{Scale: pdfInfoScale}
+
+	info := PDFDocumentInfo{}
+
+	for i, n := 0, 1; i < n; i++ {
+		page, err := loadPDFPage(buf, i, opts)
+		if err != nil {
+			return PDFDocumentInfo{}, err
+		}
+
+		if i == 0 {
+			n = page.Pages()
+			info.Pages = n
+		}
+
+		info.PageSizes = append(info.PageSizes, PageSize{
+			Width:  float64(page.Width()) / pdfInfoScale,
+			Height: float64(page.Height()) / pdfInfoScale,
+		})
+		page.Clear()
+	}
+
+	return info, nil
+}
+
+// ForEachPDFPage renders the pages one at a time and calls fn with the page index from 0. The image is
+// cleared after fn returns. Iteration stops on the first error.
+func ForEachPDFPage(buf []byte, opts PDFPageOptions, fn func(i int, img *VipsImage) error) error {
+	for i, n := 0, 1; i < n; i++ {
+		img, err := loadPDFPage(buf, i, opts)
+		if err != nil {
+			return err
+		}
+
+		if i == 0 {
+			n = img.Pages()
+		}
+
+		err = fn(i, img)
+		img.Clear()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func loadPDFPage(buf []byte, page int, opts PDFPageOptions) (*VipsImage, error) {
+	if FormatByMagicNumber(buf) != PDF {
+		return nil, ErrInvalidFileFormat
+	}
+
+	if opts.DPI < 0 {
+		return nil, fmt.Errorf("invalid DPI value %g", opts.DPI)
+	}
+
+	if opts.Scale < 0 {
+		return nil, fmt.Errorf("invalid scale value %g", opts.Scale)
+	}
+
+	params := C.PdfLoadParams{
+		dpi:        C.double(floatOr(opts.DPI, DefaultPDFPageOptions.DPI)),
+		scale:      C.double(floatOr(opts.Scale, DefaultPDFPageOptions.Scale)),
+		background: pdfBackground(opts.Background),
+	}
+
+	if opts.Password != "" {
+		params.password = C.CString(opts.Password)
+		defer C.free(unsafe.Pointer(params.password))
+	}
+
+	img := &VipsImage{}
+
+	if C.vips_pdf_load_page_go(unsafe.Pointer(&buf[0]), C.size_t(len(buf)), &img.img, C.int(page), &params) != 0 {
+		return nil, vipsError("PDFPage")
+	}
+
+	return img, nil
+}
+
+// pdfBackground returns non-premultiplied RGBA, white for nil
+func pdfBackground(c color.Color) [4]C.double {
+	if c == nil {
+		return [4]C.double{255, 255, 255, 255}
+	}
+
+	nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+
+	return [4]C.double{C.double(nc.R), C.double(nc.G), C.double(nc.B), C.double(nc.A)}
+}
@@ -0,0 +1,138 @@
+package libvips_go
+
+import (
+	"errors"
+	"image/color"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestPdfBackground(t *testing.T) {
+	tests := []struct {
+		name string
+		c    color.Color
+		want [4]float64
+	}{
+		{"Default", nil, [4]float64{255, 255, 255, 255}},
+		{"Opaque", color.RGBA{10, 20, 30, 255}, [4]float64{10, 20, 30, 255}},
+		{"Premultiplied", color.RGBA{50, 0, 0, 128}, [4]float64{99, 0, 0, 128}},
+		{"Gray", color.Gray{200}, [4]float64{200, 200, 200, 255}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pdfBackground(tt.c)
+			for i := range got {
+				if float64(got[i]) != tt.want[i] {
+					t.Errorf("pdfBackground() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPDFPage_InvalidInput(t *testing.T) {
+	pdf, err := os.ReadFile(".test/blank.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	png, err := os.ReadFile(".test/blank.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		buf     []byte
+		opts    PDFPageOptions
+		wantErr error
+	}{
+		{"NotPDF", png, DefaultPDFPageOptions, ErrInvalidFileFormat},
+		{"NegativeDPI", pdf, PDFPageOptions{DPI: -1}, nil},
+		{"NegativeScale", pdf, PDFPageOptions{DPI: 72, Scale: -1}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			err := ForEachPDFPage(tt.buf, tt.opts, func(int, *VipsImage) error {
+				called = true
+				return nil
+			})
+			if err == nil || called {
+				t.Errorf("ForEachPDFPage() error = %v, called = %v, want error without calls", err, called)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ForEachPDFPage() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func startPDF(t *testing.T) []byte {
+	t.Helper()
+
+	startVips(t)
+	if !operationAvailable("pdfload_buffer") {
+		t.Skip("the pdf loader is not available")
+	}
+
+	return readFixture(t, "blank.pdf")
+}
+
+func TestPDFInfo(t *testing.T) {
+	pdf := startPDF(t)
+
+	info, err := PDFInfo(pdf)
+	if err != nil {
+		t.Fatalf("PDFInfo() error = %v", err)
+	}
+
+	want := PDFDocumentInfo{Pages: 1, PageSizes: []PageSize{PageSizeLetter}}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("PDFInfo() got = %+v, want %+v", info, want)
+	}
+}
+
+func TestForEachPDFPage(t *testing.T) {
+	pdf := startPDF(t)
+
+	tests := []struct {
+		name  string
+		opts  PDFPageOptions
+		wantW int
+		wantH int
+	}{
+		{"Zero", PDFPageOptions{}, 612, 792},
+		{"Default", DefaultPDFPageOptions, 612, 792},
+		{"DPI", PDFPageOptions{DPI: 144}, 1224, 1584},
+		{"Scale", PDFPageOptions{Scale: 0.5, Background: color.Black}, 306, 396},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := 0
+			err := ForEachPDFPage(pdf, tt.opts, func(i int, img *VipsImage) error {
+				pages++
+
+				if i != 0 || img.Width() != tt.wantW || img.Height() != tt.wantH {
+					t.Errorf("ForEachPDFPage() page %d = %dx%d, want 0 of %dx%d", i, img.Width(), img.Height(), tt.wantW, tt.wantH)
+				}
+
+				_, err := img.Save(PNG, DefaultEncodeConfig)
+				return err
+			})
+			if err != nil {
+				t.Fatalf("ForEachPDFPage() error = %v", err)
+			}
+			if pages != 1 {
+				t.Errorf("ForEachPDFPage() visited %d pages, want 1", pages)
+			}
+		})
+	}
+
+	stop := errors.New("stop")
+	if err := ForEachPDFPage(pdf, PDFPageOptions{}, func(int, *VipsImage) error { return stop }); err != stop {
+		t.Errorf("ForEachPDFPage() error = %v, want the callback error", err)
+	}
+}
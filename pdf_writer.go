@@ -0,0 +1,411 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+type PDFFit int
+type PDFCompression int
+
+const (
+	// PDFFitContain scales the image to fit into the page keeping the aspect ratio
+	PDFFitContain PDFFit = iota
+	// PDFFitStretch scales the image to the page ignoring the aspect ratio
+	PDFFitStretch
+	// PDFFitNone keeps the image size at the DPI
+	PDFFitNone
+)
+
+const (
+	PDFCompressionJPEG PDFCompression = iota
+	// PDFCompressionFlate is lossless, but produces much bigger files for photos
+	PDFCompressionFlate
+)
+
+// PageSize is measured in points, 1/72 of an inch
+type PageSize struct {
+	Width, Height float64
+}
+
+var (
+	PageSizeA4     = PageSize{595.28, 841.89}
+	PageSizeLetter = PageSize{612, 792}
+)
+
+type PDFOptions struct {
+	// PageSize zero value sizes every page to its image at the DPI
+	PageSize PageSize
+	// DPI is the image resolution used by PDFFitNone and the page sizing
+	DPI float64
+	// Margin is the blank space on every side of the page, in points
+	Margin      float64
+	Fit         PDFFit
+	Compression PDFCompression
+	// Quality of the JPEG compression, zero is the DefaultPDFOptions quality
+	Quality int
+
+	Title   string
+	Author  string
+	Subject string
+	Creator string
+}
+
+var DefaultPDFOptions = PDFOptions{
+	PageSize:    PageSizeA4,
+	DPI:         72,
+	Fit:         PDFFitContain,
+	Compression: PDFCompressionJPEG,
+	Quality:     85,
+	Creator:     "libvips-go",
+}
+
+type pdfImage struct {
+	width, height int
+	// filter is the stream filter of data, e.g. DCTDecode
+	filter     string
+	colorSpace string
+	// decode inverts the Adobe CMYK JPEG
+	decode string
+	data   []byte
+	// alpha is the uncompressed soft mask, nil for opaque images
+	alpha []byte
+}
+
+// SavePDF writes a PDF document with a page per image. It doesn't need image magick. Pages with alpha
+// compressed as JPEG are left decoded in memory, like after CopyMemory.
+func SavePDF(pages []*VipsImage, opts PDFOptions) ([]byte, error) {
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no PDF pages")
+	}
+
+	images := make([]pdfImage, 0, len(pages))
+	for _, page := range pages {
+		im, err := page.pdfImage(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, im)
+	}
+
+	return writePDF(images, opts)
+}
+
+func (img *VipsImage) pdfImage(opts PDFOptions) (pdfImage, error) {
+	im := pdfImage{width: img.Width(), height: img.Height()}
+
+	// The JPEG of an image with alpha is a second pass over the pixels, the loaders read them only once.
+	// The image is decoded into memory in place and stays usable.
+	if opts.Compression == PDFCompressionJPEG && img.HasAlpha() {
+		if err := img.CopyMemory(); err != nil {
+			return pdfImage{}, err
+		}
+	}
+
+	var rgba []byte
+	if opts.Compression == PDFCompressionFlate || img.HasAlpha() {
+		var err error
+		if rgba, err = img.rgbaPixels(); err != nil {
+			return pdfImage{}, err
+		}
+
+		if img.HasAlpha() {
+			im.alpha = make([]byte, 0, im.width*im.height)
+			for i := 3; i < len(rgba); i += 4 {
+				im.alpha = append(im.alpha, rgba[i])
+			}
+		}
+	}
+
+	switch opts.Compression {
+	case PDFCompressionJPEG:
+		ec := DefaultEncodeConfig
+		ec.Quality(intOr(opts.Quality, DefaultPDFOptions.Quality))
+		ec.StripMetadata(true)
+		ec.Interlace(false)
+
+		buf, err := img.Save(JPEG, ec)
+		if err != nil {
+			return pdfImage{}, err
+		}
+
+		components, err := jpegComponents(buf)
+		if err != nil {
+			return pdfImage{}, err
+		}
+
+		im.filter, im.data = "DCTDecode", buf
+		switch components {
+		case 1:
+			im.colorSpace = "DeviceGray"
+		case 3:
+			im.colorSpace = "DeviceRGB"
+		case 4:
+			im.colorSpace, im.decode = "DeviceCMYK", "[1 0 1 0 1 0 1 0]"
+		default:
+			return pdfImage{}, fmt.Errorf("unsupported number of JPEG components %d", components)
+		}
+	case PDFCompressionFlate:
+		rgb := make([]byte, 0, im.width*im.height*3)
+		for i := 0; i+3 < len(rgba); i += 4 {
+			rgb = append(rgb, rgba[i:i+3]...)
+		}
+
+		data, err := deflate(rgb)
+		if err != nil {
+			return pdfImage{}, err
+		}
+
+		im.filter, im.colorSpace, im.data = "FlateDecode", "DeviceRGB", data
+	default:
+		return pdfImage{}, fmt.Errorf("invalid PDF compression %d", opts.Compression)
+	}
+
+	return im, nil
+}
+
+// jpegComponents returns the number of colour components from the frame header
+func jpegComponents(buf []byte) (int, error) {
+	if !bytes.HasPrefix(buf, jpegMagic) {
+		return 0, fmt.Errorf("%w: invalid JPEG header", ErrCorruptImage)
+	}
+
+	for i := 2; i+4 <= len(buf); {
+		if buf[i] != 0xFF {
+			return 0, fmt.Errorf("%w: invalid JPEG marker", ErrCorruptImage)
+		}
+
+		marker := buf[i+1]
+		// Fill bytes
+		if marker == 0xFF {
+			i++
+			continue
+		}
+
+		length := int(buf[i+2])<<8 | int(buf[i+3])
+		// SOF markers, except DHT, JPG and DAC
+		if marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC {
+			if i+9 >= len(buf) {
+				break
+			}
+
+			return int(buf[i+9]), nil
+		}
+
+		if marker == 0xDA {
+			break
+		}
+
+		i += 2 + length
+	}
+
+	return 0, fmt.Errorf("%w: JPEG frame header not found", ErrCorruptImage)
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+// object writes the numbered object, the numbers start from 1 and go in order
+func (w *pdfWriter) object(body string, stream []byte) {
+	w.offsets = append(w.offsets, w.buf.Len())
+
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\n", len(w.offsets), body)
+	if stream != nil {
+		w.buf.WriteString("stream\n")
+		w.buf.Write(stream)
+		w.buf.WriteString("\nendstream\n")
+	}
+	w.buf.WriteString("endobj\n")
+}
+
+// pageLayout returns the page size and the image placement, all in points
+func pageLayout(im pdfImage, opts PDFOptions) (pageW, pageH, x, y, w, h float64) {
+	dpi := opts.DPI
+	if dpi <= 0 {
+		dpi = 72
+	}
+
+	natW, natH := float64(im.width)*72/dpi, float64(im.height)*72/dpi
+
+	pageW, pageH = opts.PageSize.Width, opts.PageSize.Height
+	if pageW <= 0 || pageH <= 0 {
+		pageW, pageH = natW+2*opts.Margin, natH+2*opts.Margin
+	}
+
+	boxW, boxH := pageW-2*opts.Margin, pageH-2*opts.Margin
+	if boxW < 0 {
+		boxW = 0
+	}
+	if boxH < 0 {
+		boxH = 0
+	}
+
+	switch opts.Fit {
+	case PDFFitStretch:
+		w, h = boxW, boxH
+	case PDFFitNone:
+		w, h = natW, natH
+	default:
+		scale := boxW / natW
+		if s := boxH / natH; s < scale {
+			scale = s
+		}
+		w, h = natW*scale, natH*scale
+	}
+
+	return pageW, pageH, opts.Margin + (boxW-w)/2, opts.Margin + (boxH-h)/2, w, h
+}
+
+// writePDF lays out a page per image. Objects are: catalog, page tree, info, then page, content,
+// image and optional soft mask of every page.
+func writePDF(images []pdfImage, opts PDFOptions) ([]byte, error) {
+	w := &pdfWriter{}
+	w.buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	const firstPage = 4
+
+	kids := make([]string, 0, len(images))
+	obj := firstPage
+	for _, im := range images {
+		kids = append(kids, fmt.Sprintf("%d 0 R", obj))
+
+		obj += 3
+		if im.alpha != nil {
+			obj++
+		}
+	}
+
+	w.object("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	w.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(images)), nil)
+
+	info := []string{}
+	for _, field := range []struct{ key, val string }{
+		{"Title", opts.Title},
+		{"Author", opts.Author},
+		{"Subject", opts.Subject},
+		{"Creator", opts.Creator},
+		{"Producer", "libvips-go"},
+	} {
+		if field.val != "" {
+			info = append(info, "/"+field.key+" "+pdfString(field.val))
+		}
+	}
+	w.object("<< "+strings.Join(info, " ")+" >>", nil)
+
+	for _, im := range images {
+		page := len(w.offsets) + 1
+		pageW, pageH, x, y, imgW, imgH := pageLayout(im, opts)
+
+		w.object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Contents %d 0 R "+
+			"/Resources << /XObject << /Im0 %d 0 R >> >> >>",
+			pdfNumber(pageW), pdfNumber(pageH), page+1, page+2), nil)
+
+		content := fmt.Sprintf("q %s 0 0 %s %s %s cm /Im0 Do Q",
+			pdfNumber(imgW), pdfNumber(imgH), pdfNumber(x), pdfNumber(y))
+		w.object(fmt.Sprintf("<< /Length %d >>", len(content)), []byte(content))
+
+		dict := fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s "+
+			"/BitsPerComponent 8 /Filter /%s /Length %d", im.width, im.height, im.colorSpace, im.filter, len(im.data))
+		if im.decode != "" {
+			dict += " /Decode " + im.decode
+		}
+		if im.alpha != nil {
+			dict += fmt.Sprintf(" /SMask %d 0 R", page+3)
+		}
+		w.object(dict+" >>", im.data)
+
+		if im.alpha != nil {
+			alpha, err := deflate(im.alpha)
+			if err != nil {
+				return nil, err
+			}
+
+			w.object(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray "+
+				"/BitsPerComponent 8 /Filter /FlateDecode /Length %d >>", im.width, im.height, len(alpha)), alpha)
+		}
+	}
+
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(w.offsets)+1, xref)
+
+	return w.buf.Bytes(), nil
+}
+
+func pdfNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 32)
+}
+
+// pdfString returns a literal string for ASCII text and UTF-16 hex string otherwise
+func pdfString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e {
+			ascii = false
+			break
+		}
+	}
+
+	if ascii {
+		return "(" + strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s) + ")"
+	}
+
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, c := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", c)
+	}
+	b.WriteString(">")
+
+	return b.String()
+}
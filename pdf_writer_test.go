@@ -0,0 +1,171 @@
+package libvips_go
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestJpegComponents(t *testing.T) {
+	blank, err := os.ReadFile(".test/blank.jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		buf     []byte
+		want    int
+		wantErr bool
+	}{
+		{"Blank", blank, 3, false},
+		{"Gray", []byte{0xFF, 0xD8, 0xFF, 0xC0, 0, 11, 8, 0, 1, 0, 1, 1, 1, 0x11, 0}, 1, false},
+		{"FillBytesAndApp", []byte{0xFF, 0xD8, 0xFF, 0xFF, 0xE0, 0, 2, 0xFF, 0xC2, 0, 20, 8, 0, 1, 0, 1, 4}, 4, false},
+		{"NoFrame", []byte{0xFF, 0xD8, 0xFF, 0xDA, 0, 2}, 0, true},
+		{"NotJPEG", []byte("\x89PNG\r\n"), 0, true},
+		{"Truncated", []byte{0xFF, 0xD8, 0xFF, 0xC0, 0, 11, 8}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jpegComponents(tt.buf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("jpegComponents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("jpegComponents() got = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageLayout(t *testing.T) {
+	im := pdfImage{width: 200, height: 100}
+
+	tests := []struct {
+		name string
+		opts PDFOptions
+		want [6]float64
+	}{
+		{"Contain", PDFOptions{PageSize: PageSize{300, 300}, Margin: 50}, [6]float64{300, 300, 50, 100, 200, 100}},
+		{"Stretch", PDFOptions{PageSize: PageSize{300, 300}, Margin: 50, Fit: PDFFitStretch}, [6]float64{300, 300, 50, 50, 200, 200}},
+		{"None", PDFOptions{PageSize: PageSize{300, 300}, DPI: 144, Fit: PDFFitNone}, [6]float64{300, 300, 100, 125, 100, 50}},
+		{"PageByImage", PDFOptions{DPI: 144, Margin: 10}, [6]float64{120, 70, 10, 10, 100, 50}},
+		{"DefaultDPI", PDFOptions{}, [6]float64{200, 100, 0, 0, 200, 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pageW, pageH, x, y, w, h := pageLayout(im, tt.opts)
+			if got := [6]float64{pageW, pageH, x, y, w, h}; got != tt.want {
+				t.Errorf("pageLayout() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPdfString(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"ASCII", "Report", "(Report)"},
+		{"Escaped", `a(b)\c`, `(a\(b\)\\c)`},
+		{"Unicode", "Отчёт", "<FEFF041E0442044704510442>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pdfString(tt.in); got != tt.want {
+				t.Errorf("pdfString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	images := []pdfImage{
+		{width: 2, height: 1, filter: "FlateDecode", colorSpace: "DeviceRGB", data: []byte{1, 2, 3}},
+		{width: 1, height: 1, filter: "DCTDecode", colorSpace: "DeviceCMYK", decode: "[1 0 1 0 1 0 1 0]",
+			data: []byte{4}, alpha: []byte{128}},
+	}
+
+	opts := DefaultPDFOptions
+	opts.Title = "Test"
+
+	buf, err := writePDF(images, opts)
+	if err != nil {
+		t.Fatalf("writePDF() error = %v", err)
+	}
+
+	if f := FormatByMagicNumber(buf); f != PDF {
+		t.Errorf("FormatByMagicNumber() = %v, want PDF", f)
+	}
+
+	for _, want := range []string{"/Kids [4 0 R 7 0 R] /Count 2", "/Title (Test)", "/SMask 10 0 R", "/Decode [1 0 1 0 1 0 1 0]"} {
+		if !bytes.Contains(buf, []byte(want)) {
+			t.Errorf("writePDF() output doesn't contain %q", want)
+		}
+	}
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(buf)
+	if m == nil {
+		t.Fatal("writePDF() output has no startxref")
+	}
+
+	xref, _ := strconv.Atoi(string(m[1]))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(buf[xref:], -1)
+	if len(entries) != 10 {
+		t.Fatalf("writePDF() xref has %d objects, want 10", len(entries))
+	}
+
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(buf[offset:], []byte(want)) {
+			t.Errorf("writePDF() xref entry %d points to %q", i+1, buf[offset:offset+10])
+		}
+	}
+}
+
+func TestSavePDF(t *testing.T) {
+	startVips(t)
+
+	tests := []struct {
+		name    string
+		fixture string
+		opts    PDFOptions
+	}{
+		{"AlphaJPEG", "blank.png", PDFOptions{Compression: PDFCompressionJPEG}},
+		{"AlphaFlate", "blank.png", PDFOptions{Compression: PDFCompressionFlate}},
+		{"OpaqueJPEG", "blank.jpeg", DefaultPDFOptions},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Every page is loaded lazily, like the pages a caller passes in
+			img := loadFixture(t, tt.fixture)
+
+			buf, err := SavePDF([]*VipsImage{img}, tt.opts)
+			if err != nil {
+				t.Fatalf("SavePDF() error = %v", err)
+			}
+
+			if got := FormatByMagicNumber(buf); got != PDF {
+				t.Errorf("SavePDF() wrote %s, want PDF", got)
+			}
+
+			if img.HasAlpha() && !bytes.Contains(buf, []byte("/SMask")) {
+				t.Errorf("SavePDF() has no soft mask for the image with alpha")
+			}
+
+			// The page read twice is decoded in place and stays usable
+			if tt.opts.Compression != PDFCompressionJPEG || !img.HasAlpha() {
+				return
+			}
+
+			if _, err = img.Save(PNG, DefaultEncodeConfig); err != nil {
+				t.Errorf("Save() after SavePDF() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"unsafe"
+)
+
+type Interpolator int
+
+const (
+	InterpolatorBilinear Interpolator = iota
+	InterpolatorBicubic
+	InterpolatorNohalo
+	InterpolatorLBB
+	InterpolatorNearest
+)
+
+func (interp Interpolator) nickname() (string, error) {
+	switch interp {
+	case InterpolatorBilinear:
+		return "bilinear", nil
+	case InterpolatorBicubic:
+		return "bicubic", nil
+	case InterpolatorNohalo:
+		return "nohalo", nil
+	case InterpolatorLBB:
+		return "lbb", nil
+	case InterpolatorNearest:
+		return "nearest", nil
+	}
+
+	return "", fmt.Errorf("not a valid interpolator %d", interp)
+}
+
+// RotateAny rotates the image clockwise by any angle, the canvas is expanded to fit the rotated image.
+// The corners are filled with bg, or left transparent if it's nil. Alpha channel is added if the image
+// has none and the background isn't opaque. Multiples of 90 degrees are rotated exactly. Other angles
+// need 8 or 16-bit gray or RGB image, the image is decoded into memory for them.
+func (img *VipsImage) RotateAny(degrees float64, bg color.Color, interp Interpolator) error {
+	nickname, err := interp.nickname()
+	if err != nil {
+		return err
+	}
+
+	if math.IsNaN(degrees) || math.IsInf(degrees, 0) {
+		return fmt.Errorf("invalid rotation angle %g", degrees)
+	}
+
+	degrees = math.Mod(degrees, 360)
+	if degrees < 0 {
+		degrees += 360
+	}
+
+	if math.Mod(degrees, 90) == 0 {
+		if degrees == 0 {
+			return nil
+		}
+
+		return img.Rotate(int(degrees))
+	}
+
+	maxValue, err := rotateMaxValue(img.BandFormat(), img.Interpretation())
+	if err != nil {
+		return err
+	}
+
+	// The rotation reads the rows out of order, the loaders give sequential access only
+	if err = img.CopyMemory(); err != nil {
+		return err
+	}
+
+	// The opaque alpha of 16-bit images is 65535, addalpha picks it by the interpretation
+	if _, _, _, a := backgroundRGBA(bg); a < 0xffff && !img.HasAlpha() {
+		var tmp *C.VipsImage
+		if C.vips_addalpha_go(img.img, &tmp) != 0 {
+			return vipsError("RotateAny")
+		}
+
+		C.swap_and_clear_go(&img.img, tmp)
+	}
+
+	background := rotateBackground(bg, img.Bands(), img.HasAlpha(), maxValue)
+
+	cInterp := C.CString(nickname)
+	defer C.free(unsafe.Pointer(cInterp))
+
+	var tmp *C.VipsImage
+
+	if C.vips_rotate_any_go(img.img, &tmp, C.double(degrees), cInterp,
+		(*C.double)(unsafe.Pointer(&background[0])), C.int(len(background))) != 0 {
+		return vipsError("RotateAny")
+	}
+
+	C.swap_and_clear_go(&img.img, tmp)
+
+	return nil
+}
+
+// rotateMaxValue returns the white of the image the background colour is scaled to. Colour spaces other
+// than gray and RGB, e.g. CMYK or scRGB, have no RGB background.
+func rotateMaxValue(format BandFormat, interp Interpretation) (float64, error) {
+	switch interp {
+	case InterpretationBW, InterpretationSRGB, InterpretationRGB, InterpretationGrey16, InterpretationRGB16:
+	default:
+		return 0, fmt.Errorf("unsupported interpretation %d for the rotation background", interp)
+	}
+
+	switch format {
+	case BandFormatUChar:
+		return 255, nil
+	case BandFormatUShort:
+		return 65535, nil
+	}
+
+	return 0, fmt.Errorf("unsupported band format %d for the rotation background", format)
+}
+
+// backgroundRGBA returns premultiplied 16-bit components, transparent black for nil
+func backgroundRGBA(bg color.Color) (r, g, b, a uint32) {
+	if bg == nil {
+		return 0, 0, 0, 0
+	}
+
+	return bg.RGBA()
+}
+
+// rotateBackground returns premultiplied value of every band: gray or RGB, then alpha if the image has it
+func rotateBackground(bg color.Color, bands int, hasAlpha bool, maxValue float64) []float64 {
+	r, g, b, a := backgroundRGBA(bg)
+
+	scale := func(v uint32) float64 {
+		return float64(v) * maxValue / 0xffff
+	}
+
+	colorBands := bands
+	if hasAlpha {
+		colorBands--
+	}
+
+	values := make([]float64, 0, bands)
+	if colorBands < 3 {
+		// Same weights as color.GrayModel
+		y := (19595*r + 38470*g + 7471*b + 1<<15) >> 16
+		for i := 0; i < colorBands; i++ {
+			values = append(values, scale(y))
+		}
+	} else {
+		values = append(values, scale(r), scale(g), scale(b))
+	}
+
+	if hasAlpha {
+		values = append(values, scale(a))
+	}
+
+	return values
+}
@@ -0,0 +1,193 @@
+package libvips_go
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestRotateBackground(t *testing.T) {
+	tests := []struct {
+		name     string
+		bg       color.Color
+		bands    int
+		hasAlpha bool
+		maxValue float64
+		want     []float64
+	}{
+		{"TransparentRGBA", nil, 4, true, 255, []float64{0, 0, 0, 0}},
+		{"OpaqueRGB", color.RGBA{255, 128, 0, 255}, 3, false, 255, []float64{255, 128, 0}},
+		{"PremultipliedRGBA", color.NRGBA{255, 0, 0, 51}, 4, true, 255, []float64{51, 0, 0, 51}},
+		{"Gray", color.RGBA{255, 255, 255, 255}, 1, false, 255, []float64{255}},
+		{"GrayAlpha", color.Gray{100}, 2, true, 255, []float64{100, 255}},
+		{"RGB16", color.White, 3, false, 65535, []float64{65535, 65535, 65535}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rotateBackground(tt.bg, tt.bands, tt.hasAlpha, tt.maxValue); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rotateBackground() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRotateMaxValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  BandFormat
+		interp  Interpretation
+		want    float64
+		wantErr bool
+	}{
+		{"sRGB", BandFormatUChar, InterpretationSRGB, 255, false},
+		{"Gray", BandFormatUChar, InterpretationBW, 255, false},
+		{"RGB16", BandFormatUShort, InterpretationRGB16, 65535, false},
+		{"Grey16", BandFormatUShort, InterpretationGrey16, 65535, false},
+		{"CMYK", BandFormatUChar, InterpretationCMYK, 0, true},
+		{"scRGB", BandFormatFloat, InterpretationScRGB, 0, true},
+		{"FloatRGB", BandFormatFloat, InterpretationSRGB, 0, true},
+		{"Lab", BandFormatFloat, InterpretationLab, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rotateMaxValue(tt.format, tt.interp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rotateMaxValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("rotateMaxValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVipsImage_RotateAny(t *testing.T) {
+	startVips(t)
+
+	tests := []struct {
+		name      string
+		fixture   string
+		degrees   float64
+		bg        color.Color
+		wantW     int
+		wantH     int
+		wantAlpha bool
+	}{
+		{"Transparent", "wiki_a4.png", 30, nil, 1939, 2159, true},
+		{"Opaque", "blank.jpeg", -45, color.White, 2, 2, false},
+		{"Exact", "blank.png", 450, nil, 2, 3, true},
+		{"Zero", "blank.png", 360, nil, 3, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := loadFixture(t, tt.fixture)
+			hadAlpha := img.HasAlpha()
+
+			if err := img.RotateAny(tt.degrees, tt.bg, InterpolatorBilinear); err != nil {
+				t.Fatalf("RotateAny() error = %v", err)
+			}
+
+			if math.Abs(float64(img.Width()-tt.wantW)) > 2 || math.Abs(float64(img.Height()-tt.wantH)) > 2 {
+				t.Errorf("RotateAny() got = %dx%d, want about %dx%d", img.Width(), img.Height(), tt.wantW, tt.wantH)
+			}
+
+			if img.HasAlpha() != (tt.wantAlpha || hadAlpha) {
+				t.Errorf("RotateAny() HasAlpha = %v, want %v", img.HasAlpha(), tt.wantAlpha || hadAlpha)
+			}
+
+			// The pixels of the sequentially loaded image are read only now
+			if _, err := img.Save(PNG, DefaultEncodeConfig); err != nil {
+				t.Errorf("Save() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestInterpolator_nickname(t *testing.T) {
+	tests := []struct {
+		interp  Interpolator
+		want    string
+		wantErr bool
+	}{
+		{InterpolatorBilinear, "bilinear", false},
+		{InterpolatorBicubic, "bicubic", false},
+		{InterpolatorNohalo, "nohalo", false},
+		{InterpolatorLBB, "lbb", false},
+		{InterpolatorNearest, "nearest", false},
+		{Interpolator(42), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got, err := tt.interp.nickname()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("nickname() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("nickname() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// png16 encodes an opaque 16-bit RGB image
+func png16(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	src := image.NewRGBA64(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			src.SetRGBA64(x, y, color.RGBA64{R: 0xffff, G: 0x8000, A: 0xffff})
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestVipsImage_RotateAny_16Bit(t *testing.T) {
+	startVips(t)
+
+	tests := []struct {
+		name string
+		bg   color.Color
+	}{
+		{"Transparent", nil},
+		{"Translucent", color.NRGBA{R: 255, A: 128}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Load(png16(t, 40, 30))
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			defer img.Clear()
+
+			if img.Interpretation() != InterpretationRGB16 || img.HasAlpha() {
+				t.Fatalf("Load() got = %d interpretation, alpha %v, want opaque RGB16", img.Interpretation(), img.HasAlpha())
+			}
+
+			if err = img.RotateAny(30, tt.bg, InterpolatorBilinear); err != nil {
+				t.Fatalf("RotateAny() error = %v", err)
+			}
+
+			rgba, err := img.rgbaPixels()
+			if err != nil {
+				t.Fatalf("rgbaPixels() error = %v", err)
+			}
+
+			// The centre is inside the rotated image, it stays opaque
+			w, h := img.Width(), img.Height()
+			if a := rgba[((h/2)*w+w/2)*4+3]; a != 255 {
+				t.Errorf("RotateAny() alpha of the centre = %d, want 255", a)
+			}
+		})
+	}
+}
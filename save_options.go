@@ -0,0 +1,532 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"io"
+	"runtime/cgo"
+	"unsafe"
+)
+
+type Subsample int
+type PngFilter int
+type WebpPreset int
+type TiffCompression int
+type TiffPredictor int
+
+const (
+	SubsampleAuto = Subsample(C.VIPS_FOREIGN_SUBSAMPLE_AUTO)
+	SubsampleOn   = Subsample(C.VIPS_FOREIGN_SUBSAMPLE_ON)
+	SubsampleOff  = Subsample(C.VIPS_FOREIGN_SUBSAMPLE_OFF)
+
+	PngFilterNone  = PngFilter(C.VIPS_FOREIGN_PNG_FILTER_NONE)
+	PngFilterSub   = PngFilter(C.VIPS_FOREIGN_PNG_FILTER_SUB)
+	PngFilterUp    = PngFilter(C.VIPS_FOREIGN_PNG_FILTER_UP)
+	PngFilterAvg   = PngFilter(C.VIPS_FOREIGN_PNG_FILTER_AVG)
+	PngFilterPaeth = PngFilter(C.VIPS_FOREIGN_PNG_FILTER_PAETH)
+	PngFilterAll   = PngFilter(C.VIPS_FOREIGN_PNG_FILTER_ALL)
+
+	WebpPresetDefault = WebpPreset(C.VIPS_FOREIGN_WEBP_PRESET_DEFAULT)
+	WebpPresetPicture = WebpPreset(C.VIPS_FOREIGN_WEBP_PRESET_PICTURE)
+	WebpPresetPhoto   = WebpPreset(C.VIPS_FOREIGN_WEBP_PRESET_PHOTO)
+	WebpPresetDrawing = WebpPreset(C.VIPS_FOREIGN_WEBP_PRESET_DRAWING)
+	WebpPresetIcon    = WebpPreset(C.VIPS_FOREIGN_WEBP_PRESET_ICON)
+	WebpPresetText    = WebpPreset(C.VIPS_FOREIGN_WEBP_PRESET_TEXT)
+
+	TiffCompressionNone      = TiffCompression(C.VIPS_FOREIGN_TIFF_COMPRESSION_NONE)
+	TiffCompressionJpeg      = TiffCompression(C.VIPS_FOREIGN_TIFF_COMPRESSION_JPEG)
+	TiffCompressionDeflate   = TiffCompression(C.VIPS_FOREIGN_TIFF_COMPRESSION_DEFLATE)
+	TiffCompressionPackbits  = TiffCompression(C.VIPS_FOREIGN_TIFF_COMPRESSION_PACKBITS)
+	TiffCompressionCCITTFax4 = TiffCompression(C.VIPS_FOREIGN_TIFF_COMPRESSION_CCITTFAX4)
+	TiffCompressionLZW       = TiffCompression(C.VIPS_FOREIGN_TIFF_COMPRESSION_LZW)
+	TiffCompressionWebp      = TiffCompression(C.VIPS_FOREIGN_TIFF_COMPRESSION_WEBP)
+	TiffCompressionZstd      = TiffCompression(C.VIPS_FOREIGN_TIFF_COMPRESSION_ZSTD)
+
+	TiffPredictorNone       = TiffPredictor(C.VIPS_FOREIGN_TIFF_PREDICTOR_NONE)
+	TiffPredictorHorizontal = TiffPredictor(C.VIPS_FOREIGN_TIFF_PREDICTOR_HORIZONTAL)
+	TiffPredictorFloat      = TiffPredictor(C.VIPS_FOREIGN_TIFF_PREDICTOR_FLOAT)
+)
+
+// Saver is implemented by the per-format option structs. The options are mapped onto the
+// libvips 8.14 saver arguments, older versions reject the arguments they don't know.
+type Saver interface {
+	Format() ImageFormat
+	// save writes the image to the target if it's set, to buf otherwise
+	save(in *C.VipsImage, target *C.VipsTarget, buf *unsafe.Pointer, size *C.size_t) C.int
+}
+
+type JpegOptions struct {
+	Quality            int
+	StripMetadata      bool
+	Interlace          bool
+	OptimizeCoding     bool
+	Subsample          Subsample
+	TrellisQuant       bool
+	OvershootDeringing bool
+	OptimizeScans      bool
+	QuantTable         int
+}
+
+type PngOptions struct {
+	Compression   int
+	StripMetadata bool
+	Interlace     bool
+	Filter        PngFilter
+	Palette       bool
+	// Quality, Dither and Effort are used by the palette quantisation only
+	Quality int
+	Dither  float64
+	Effort  int
+	// 0 picks the bit depth by the image format
+	Bitdepth int
+}
+
+type WebpOptions struct {
+	Quality        int
+	StripMetadata  bool
+	Lossless       bool
+	NearLossless   bool
+	SmartSubsample bool
+	AlphaQuality   int
+	Effort         int
+	Preset         WebpPreset
+}
+
+type HeifOptions struct {
+	Quality       int
+	StripMetadata bool
+	Lossless      bool
+	Compression   HEIFCompressionType
+	Effort        int
+	Subsample     Subsample
+	// 0 picks the bit depth by the image format
+	Bitdepth int
+}
+
+type AvifOptions struct {
+	Quality       int
+	StripMetadata bool
+	Lossless      bool
+	Effort        int
+	Subsample     Subsample
+	// 0 picks the bit depth by the image format
+	Bitdepth int
+}
+
+type TiffOptions struct {
+	Quality       int
+	StripMetadata bool
+	Compression   TiffCompression
+	Predictor     TiffPredictor
+	Tile          bool
+	TileWidth     int
+	TileHeight    int
+	Pyramid       bool
+}
+
+// JxlOptions require libvips 8.11+ built with libjxl
+type JxlOptions struct {
+	// Distance is the maximum acceptable error, 1.0 is visually lossless
+	Distance      float64
+	Effort        int
+	Lossless      bool
+	Tier          int
+	StripMetadata bool
+}
+
+// Jp2kOptions require libvips 8.11+ built with OpenJPEG
+type Jp2kOptions struct {
+	TileWidth     int
+	TileHeight    int
+	Lossless      bool
+	Quality       int
+	Subsample     Subsample
+	StripMetadata bool
+}
+
+// GifOptions are used by the native GIF saver of libvips 8.12+. Image magick is used without them
+// when libvips is built without cgif.
+type GifOptions struct {
+	Dither   float64
+	Effort   int
+	Bitdepth int
+	// InterframeMaxError is the maximum inter-frame error for transparency, requires libvips 8.13+
+	InterframeMaxError float64
+	// ReusePalette reuses the input palette, requires libvips 8.13+
+	ReusePalette bool
+}
+
+// Defaults match the libvips ones, start from them and change what differs. The fields are sent to libvips
+// as they are, so a zero PNG compression or WebP effort is saved as asked. Only the zero values libvips
+// rejects, like the JPEG quality or the PNG effort, are replaced by the defaults.
+
+var DefaultJpegOptions = JpegOptions{
+	Quality:   75,
+	Subsample: SubsampleAuto,
+}
+
+var DefaultPngOptions = PngOptions{
+	Compression: 6,
+	Filter:      PngFilterNone,
+	Quality:     100,
+	Dither:      1.0,
+	Effort:      7,
+}
+
+var DefaultWebpOptions = WebpOptions{
+	Quality:      75,
+	AlphaQuality: 100,
+	Effort:       4,
+	Preset:       WebpPresetDefault,
+}
+
+var DefaultHeifOptions = HeifOptions{
+	Quality:     50,
+	Compression: HEIF_COMPRESSION_HEVC,
+	Effort:      4,
+	Subsample:   SubsampleAuto,
+}
+
+var DefaultAvifOptions = AvifOptions{
+	Quality:   50,
+	Effort:    4,
+	Subsample: SubsampleAuto,
+}
+
+var DefaultTiffOptions = TiffOptions{
+	Quality:     75,
+	Compression: TiffCompressionNone,
+	Predictor:   TiffPredictorHorizontal,
+	TileWidth:   128,
+	TileHeight:  128,
+}
+
+var DefaultJxlOptions = JxlOptions{
+	Distance: 1.0,
+	Effort:   7,
+}
+
+var DefaultJp2kOptions = Jp2kOptions{
+	TileWidth:  512,
+	TileHeight: 512,
+	Quality:    48,
+	Subsample:  SubsampleAuto,
+}
+
+var DefaultGifOptions = GifOptions{
+	Dither:   1.0,
+	Effort:   7,
+	Bitdepth: 8,
+}
+
+func intOr(v, def int) int {
+	if v == 0 {
+		return def
+	}
+
+	return v
+}
+
+func floatOr(v, def float64) float64 {
+	if v == 0 {
+		return def
+	}
+
+	return v
+}
+
+func (o JpegOptions) withDefaults() JpegOptions {
+	o.Quality = intOr(o.Quality, DefaultJpegOptions.Quality)
+
+	return o
+}
+
+func (o PngOptions) withDefaults() PngOptions {
+	o.Effort = intOr(o.Effort, DefaultPngOptions.Effort)
+
+	return o
+}
+
+func (o HeifOptions) withDefaults() HeifOptions {
+	o.Quality = intOr(o.Quality, DefaultHeifOptions.Quality)
+	o.Compression = HEIFCompressionType(intOr(int(o.Compression), int(DefaultHeifOptions.Compression)))
+
+	return o
+}
+
+func (o AvifOptions) withDefaults() AvifOptions {
+	o.Quality = intOr(o.Quality, DefaultAvifOptions.Quality)
+
+	return o
+}
+
+func (o TiffOptions) withDefaults() TiffOptions {
+	o.Quality = intOr(o.Quality, DefaultTiffOptions.Quality)
+	o.Predictor = TiffPredictor(intOr(int(o.Predictor), int(DefaultTiffOptions.Predictor)))
+	o.TileWidth = intOr(o.TileWidth, DefaultTiffOptions.TileWidth)
+	o.TileHeight = intOr(o.TileHeight, DefaultTiffOptions.TileHeight)
+
+	return o
+}
+
+func (o JxlOptions) withDefaults() JxlOptions {
+	o.Effort = intOr(o.Effort, DefaultJxlOptions.Effort)
+
+	return o
+}
+
+func (o Jp2kOptions) withDefaults() Jp2kOptions {
+	o.TileWidth = intOr(o.TileWidth, DefaultJp2kOptions.TileWidth)
+	o.TileHeight = intOr(o.TileHeight, DefaultJp2kOptions.TileHeight)
+	o.Quality = intOr(o.Quality, DefaultJp2kOptions.Quality)
+
+	return o
+}
+
+func (o GifOptions) withDefaults() GifOptions {
+	o.Effort = intOr(o.Effort, DefaultGifOptions.Effort)
+	o.Bitdepth = intOr(o.Bitdepth, DefaultGifOptions.Bitdepth)
+
+	return o
+}
+
+func (o JpegOptions) Format() ImageFormat { return JPEG }
+func (o PngOptions) Format() ImageFormat  { return PNG }
+func (o WebpOptions) Format() ImageFormat { return WEBP }
+func (o HeifOptions) Format() ImageFormat { return HEIF }
+func (o AvifOptions) Format() ImageFormat { return AVIF }
+func (o TiffOptions) Format() ImageFormat { return TIFF }
+func (o JxlOptions) Format() ImageFormat  { return JXL }
+func (o Jp2kOptions) Format() ImageFormat { return JP2K }
+func (o GifOptions) Format() ImageFormat  { return GIF }
+
+func (o JpegOptions) save(in *C.VipsImage, target *C.VipsTarget, buf *unsafe.Pointer, size *C.size_t) C.int {
+	o = o.withDefaults()
+
+	params := C.JpegSaveParams{
+		quality:             C.int(o.Quality),
+		strip:               gbool(o.StripMetadata),
+		interlace:           gbool(o.Interlace),
+		optimize_coding:     gbool(o.OptimizeCoding),
+		subsample_mode:      C.int(o.Subsample),
+		trellis_quant:       gbool(o.TrellisQuant),
+		overshoot_deringing: gbool(o.OvershootDeringing),
+		optimize_scans:      gbool(o.OptimizeScans),
+		quant_table:         C.int(o.QuantTable),
+	}
+
+	return C.vips_jpegsave_params_go(in, target, buf, size, &params)
+}
+
+func (o PngOptions) save(in *C.VipsImage, target *C.VipsTarget, buf *unsafe.Pointer, size *C.size_t) C.int {
+	o = o.withDefaults()
+
+	params := C.PngSaveParams{
+		compression: C.int(o.Compression),
+		strip:       gbool(o.StripMetadata),
+		interlace:   gbool(o.Interlace),
+		filter:      C.int(o.Filter),
+		palette:     gbool(o.Palette),
+		quality:     C.int(o.Quality),
+		dither:      C.double(o.Dither),
+		effort:      C.int(o.Effort),
+		bitdepth:    C.int(o.Bitdepth),
+	}
+
+	return C.vips_pngsave_params_go(in, target, buf, size, &params)
+}
+
+func (o WebpOptions) save(in *C.VipsImage, target *C.VipsTarget, buf *unsafe.Pointer, size *C.size_t) C.int {
+	params := C.WebpSaveParams{
+		quality:         C.int(o.Quality),
+		strip:           gbool(o.StripMetadata),
+		lossless:        gbool(o.Lossless),
+		near_lossless:   gbool(o.NearLossless),
+		smart_subsample: gbool(o.SmartSubsample),
+		alpha_quality:   C.int(o.AlphaQuality),
+		effort:          C.int(o.Effort),
+		preset:          C.int(o.Preset),
+	}
+
+	return C.vips_webpsave_params_go(in, target, buf, size, &params)
+}
+
+func (o HeifOptions) save(in *C.VipsImage, target *C.VipsTarget, buf *unsafe.Pointer, size *C.size_t) C.int {
+	o = o.withDefaults()
+
+	params := C.HeifSaveParams{
+		quality:        C.int(o.Quality),
+		strip:          gbool(o.StripMetadata),
+		lossless:       gbool(o.Lossless),
+		compression:    C.int(o.Compression),
+		effort:         C.int(o.Effort),
+		subsample_mode: C.int(o.Subsample),
+		bitdepth:       C.int(o.Bitdepth),
+	}
+
+	return C.vips_heifsave_params_go(in, target, buf, size, &params)
+}
+
+func (o AvifOptions) save(in *C.VipsImage, target *C.VipsTarget, buf *unsafe.Pointer, size *C.size_t) C.int {
+	o = o.withDefaults()
+
+	return HeifOptions{
+		Quality:       o.Quality,
+		StripMetadata: o.StripMetadata,
+		Lossless:      o.Lossless,
+		Compression:   HEIFCompressionType(C.VIPS_FOREIGN_HEIF_COMPRESSION_AV1),
+		Effort:        o.Effort,
+		Subsample:     o.Subsample,
+		Bitdepth:      o.Bitdepth,
+	}.save(in, target, buf, size)
+}
+
+func (o TiffOptions) save(in *C.VipsImage, target *C.VipsTarget, buf *unsafe.Pointer, size *C.size_t) C.int {
+	o = o.withDefaults()
+
+	params := C.TiffSaveParams{
+		quality:     C.int(o.Quality),
+		strip:       gbool(o.StripMetadata),
+		compression: C.int(o.Compression),
+		predictor:   C.int(o.Predictor),
+		tile:        gbool(o.Tile),
+		tile_width:  C.int(o.TileWidth),
+		tile_height: C.int(o.TileHeight),
+		pyramid:     gbool(o.Pyramid),
+	}
+
+	return C.vips_tiffsave_params_go(in, target, buf, size, &params)
+}
+
+func (o JxlOptions) save(in *C.VipsImage, target *C.VipsTarget, buf *unsafe.Pointer, size *C.size_t) C.int {
+	o = o.withDefaults()
+
+	params := C.JxlSaveParams{
+		distance: C.double(o.Distance),
+		effort:   C.int(o.Effort),
+		lossless: gbool(o.Lossless),
+		tier:     C.int(o.Tier),
+		strip:    gbool(o.StripMetadata),
+	}
+
+	return C.vips_jxlsave_params_go(in, target, buf, size, &params)
+}
+
+// jxlOptions maps the shared encoder options to JPEG XL ones, quality is converted to distance like libvips 8.14 does
+func jxlOptions(ec encodeConfig) JxlOptions {
+	opts := DefaultJxlOptions
+	opts.Distance = jxlDistance(int(ec.quality))
+	opts.Lossless = ec.lossless != 0
+	opts.StripMetadata = ec.strip != 0
+
+	return opts
+}
+
+func jxlDistance(quality int) float64 {
+	q := float64(quality)
+	if quality >= 30 {
+		return 0.1 + (100-q)*0.09
+	}
+
+	return 53.0/3000.0*q*q - 23.0/20.0*q + 25.0
+}
+
+func (o Jp2kOptions) save(in *C.VipsImage, target *C.VipsTarget, buf *unsafe.Pointer, size *C.size_t) C.int {
+	o = o.withDefaults()
+
+	params := C.Jp2kSaveParams{
+		tile_width:     C.int(o.TileWidth),
+		tile_height:    C.int(o.TileHeight),
+		lossless:       gbool(o.Lossless),
+		quality:        C.int(o.Quality),
+		subsample_mode: C.int(o.Subsample),
+		strip:          gbool(o.StripMetadata),
+	}
+
+	return C.vips_jp2ksave_params_go(in, target, buf, size, &params)
+}
+
+// jp2kOptions maps the shared encoder options to JPEG 2000 ones
+func jp2kOptions(ec encodeConfig) Jp2kOptions {
+	opts := DefaultJp2kOptions
+	opts.Quality = int(ec.quality)
+	opts.Lossless = ec.lossless != 0
+	opts.StripMetadata = ec.strip != 0
+
+	return opts
+}
+
+func (o GifOptions) save(in *C.VipsImage, target *C.VipsTarget, buf *unsafe.Pointer, size *C.size_t) C.int {
+	o = o.withDefaults()
+
+	params := C.GifSaveParams{
+		dither:              C.double(o.Dither),
+		effort:              C.int(o.Effort),
+		bitdepth:            C.int(o.Bitdepth),
+		interframe_maxerror: C.double(o.InterframeMaxError),
+		reuse:               gbool(o.ReusePalette),
+	}
+
+	return C.vips_gifsave_params_go(in, target, buf, size, &params)
+}
+
+// SaveWith encodes the image with the format and options of the saver
+func (img *VipsImage) SaveWith(opts Saver) ([]byte, error) {
+	var ptr unsafe.Pointer
+	defer C.g_free_go(&ptr)
+
+	imgSize := C.size_t(0)
+	if opts.save(img.img, nil, &ptr, &imgSize) != 0 {
+		return nil, vipsError("SaveWith")
+	}
+
+	return C.GoBytes(ptr, C.int(imgSize)), nil
+}
+
+// SaveToWith is SaveWith writing straight into w
+func (img *VipsImage) SaveToWith(w io.Writer, opts Saver) error {
+	writer := &targetWriter{w: w}
+
+	target := C.vips_target_custom_new_go(C.uintptr_t(cgo.NewHandle(writer)))
+	defer C.g_object_unref(C.gpointer(target))
+
+	if opts.save(img.img, target, nil, nil) != 0 {
+		if writer.err != nil {
+			C.vips_error_clear()
+			return writer.err
+		}
+
+		return vipsError("SaveToWith")
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package libvips_go
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestJxlDistance(t *testing.T) {
+	tests := []struct {
+		name    string
+		quality int
+		want    float64
+	}{
+		{"Max", 100, 0.1},
+		{"Default", 90, 1.0},
+		{"Boundary", 30, 6.4},
+		{"Low", 10, 15.266666},
+		{"Min", 0, 25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jxlDistance(tt.quality); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("jxlDistance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveOptions_withDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"JpegZero", JpegOptions{}.withDefaults(), DefaultJpegOptions},
+		{"JpegSet", JpegOptions{Quality: 90, StripMetadata: true}.withDefaults(), JpegOptions{Quality: 90, StripMetadata: true}},
+		{"PngZero", PngOptions{}.withDefaults(), PngOptions{Effort: 7}},
+		{"PngDefault", DefaultPngOptions.withDefaults(), DefaultPngOptions},
+		{"PngUncompressed", PngOptions{Compression: 0, Palette: true, Dither: 0}.withDefaults(),
+			PngOptions{Palette: true, Effort: 7}},
+		{"HeifZero", HeifOptions{}.withDefaults(), HeifOptions{Quality: 50, Compression: HEIF_COMPRESSION_HEVC}},
+		{"HeifDefault", DefaultHeifOptions.withDefaults(), DefaultHeifOptions},
+		{"AvifZero", AvifOptions{}.withDefaults(), AvifOptions{Quality: 50}},
+		{"AvifDefault", DefaultAvifOptions.withDefaults(), DefaultAvifOptions},
+		{"TiffZero", TiffOptions{}.withDefaults(), DefaultTiffOptions},
+		{"TiffSet", TiffOptions{Compression: TiffCompressionLZW, Predictor: TiffPredictorNone}.withDefaults(),
+			TiffOptions{Quality: 75, Compression: TiffCompressionLZW, Predictor: TiffPredictorNone, TileWidth: 128, TileHeight: 128}},
+		{"JxlZero", JxlOptions{}.withDefaults(), JxlOptions{Effort: 7}},
+		{"JxlDefault", DefaultJxlOptions.withDefaults(), DefaultJxlOptions},
+		{"JxlLossless", JxlOptions{Lossless: true, Tier: 2}.withDefaults(), JxlOptions{Effort: 7, Lossless: true, Tier: 2}},
+		{"Jp2kZero", Jp2kOptions{}.withDefaults(), DefaultJp2kOptions},
+		{"Jp2kSet", Jp2kOptions{TileWidth: 256, Lossless: true, Subsample: SubsampleOff}.withDefaults(),
+			Jp2kOptions{TileWidth: 256, TileHeight: 512, Lossless: true, Quality: 48, Subsample: SubsampleOff}},
+		{"GifZero", GifOptions{}.withDefaults(), GifOptions{Effort: 7, Bitdepth: 8}},
+		{"GifDefault", DefaultGifOptions.withDefaults(), DefaultGifOptions},
+		{"GifSet", GifOptions{Bitdepth: 4, ReusePalette: true}.withDefaults(),
+			GifOptions{Effort: 7, Bitdepth: 4, ReusePalette: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("withDefaults() = %+v, want %+v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVipsImage_SaveWith(t *testing.T) {
+	img := loadFixture(t, "wiki_a4.png")
+	if err := img.CopyMemory(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts Saver
+	}{
+		{"JpegZero", JpegOptions{}},
+		{"JpegStrip", JpegOptions{StripMetadata: true}},
+		{"JpegDefault", DefaultJpegOptions},
+		{"PngZero", PngOptions{}},
+		{"PngPalette", PngOptions{Palette: true}},
+		{"WebpZero", WebpOptions{}},
+		{"WebpDefault", DefaultWebpOptions},
+		{"TiffZero", TiffOptions{}},
+		{"TiffTiled", TiffOptions{Tile: true, Compression: TiffCompressionDeflate}},
+		{"GifZero", GifOptions{}},
+		{"GifBitdepth", GifOptions{Bitdepth: 4}},
+		{"JxlZero", JxlOptions{}},
+		{"Jp2kZero", Jp2kOptions{}},
+	}
+
+	// The savers of optional libraries
+	optional := map[ImageFormat]string{
+		JXL:  "jxlsave_buffer",
+		JP2K: "jp2ksave_buffer",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if op, ok := optional[tt.opts.Format()]; ok && !operationAvailable(op) {
+				t.Skipf("%s is not available", op)
+			}
+
+			buf, err := img.SaveWith(tt.opts)
+			if err != nil {
+				t.Fatalf("SaveWith() error = %v", err)
+			}
+
+			if got := FormatByMagicNumber(buf); got != tt.opts.Format() {
+				t.Errorf("SaveWith() wrote %s, want %s", got, tt.opts.Format())
+			}
+
+			w := new(bytes.Buffer)
+			if err = img.SaveToWith(w, tt.opts); err != nil {
+				t.Fatalf("SaveToWith() error = %v", err)
+			}
+
+			if got := FormatByMagicNumber(w.Bytes()); got != tt.opts.Format() {
+				t.Errorf("SaveToWith() wrote %s, want %s", got, tt.opts.Format())
+			}
+		})
+	}
+}
+
+func TestVipsImage_SaveWith_ZeroValues(t *testing.T) {
+	img := loadFixture(t, "wiki_a4.png")
+	if err := img.CopyMemory(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Zero compression is libvips level 0, not the default level
+	uncompressed := DefaultPngOptions
+	uncompressed.Compression = 0
+
+	stored, err := img.SaveWith(uncompressed)
+	if err != nil {
+		t.Fatalf("SaveWith() error = %v", err)
+	}
+
+	compressed, err := img.SaveWith(DefaultPngOptions)
+	if err != nil {
+		t.Fatalf("SaveWith() error = %v", err)
+	}
+
+	if len(stored) <= len(compressed) {
+		t.Errorf("SaveWith() compression 0 wrote %d bytes, compression 6 wrote %d", len(stored), len(compressed))
+	}
+}
@@ -0,0 +1,177 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"fmt"
+	"math"
+)
+
+var ErrSizeUnreachable = fmt.Errorf("image can't be encoded within the size limit")
+
+type SizeOptions struct {
+	// Encode is used for every attempt, its quality is the highest one tried. Lossless is turned off.
+	Encode encodeConfig
+	// MinQuality is the lowest quality tried
+	MinQuality int
+	// Downscale allows to reduce the image if it doesn't fit at MinQuality
+	Downscale bool
+	// MinScale is the smallest scale factor tried when downscaling, in (0, 1]
+	MinScale float64
+}
+
+// SizeResult describes the output of SaveToSize
+type SizeResult struct {
+	Quality int
+	// Scale is the factor the image is downscaled by, 1 if it isn't downscaled
+	Scale float64
+}
+
+var DefaultSizeOptions = SizeOptions{
+	Encode:     DefaultEncodeConfig,
+	MinQuality: 10,
+	MinScale:   0.25,
+}
+
+// SaveToSize encodes the image with the highest quality which output fits into maxBytes.
+// The output is downscaled when it's allowed and the lowest quality isn't enough, the image itself
+// keeps its size. It returns the chosen quality and scale with the encoded image, or the smallest
+// output produced along with ErrSizeUnreachable. The image is decoded into memory first, like
+// CopyMemory does, since it's encoded several times, and it may be encoded again afterwards.
+func (img *VipsImage) SaveToSize(imgType ImageFormat, maxBytes int, opts SizeOptions) (SizeResult, []byte, error) {
+	switch imgType {
+	case JPEG, WEBP, AVIF, HEIF:
+	default:
+		return SizeResult{}, nil, ErrUnsupportedImageFormat
+	}
+
+	if maxBytes <= 0 {
+		return SizeResult{}, nil, fmt.Errorf("invalid max bytes value %d", maxBytes)
+	}
+
+	maxQuality := int(opts.Encode.quality)
+	if opts.MinQuality < 1 || opts.MinQuality > maxQuality {
+		return SizeResult{}, nil, fmt.Errorf("invalid min quality value %d", opts.MinQuality)
+	}
+
+	if opts.Downscale && (opts.MinScale <= 0 || opts.MinScale > 1) {
+		return SizeResult{}, nil, fmt.Errorf("invalid min scale value %g", opts.MinScale)
+	}
+
+	if err := img.CopyMemory(); err != nil {
+		return SizeResult{}, nil, err
+	}
+
+	ec := opts.Encode
+	ec.Lossless(false)
+
+	encoder := func(src *VipsImage) func(int) ([]byte, error) {
+		return func(q int) ([]byte, error) {
+			ec.Quality(q)
+			return src.Save(imgType, ec)
+		}
+	}
+
+	q, buf, err := searchQuality(opts.MinQuality, maxQuality, maxBytes, encoder(img))
+	if err != ErrSizeUnreachable || !opts.Downscale {
+		return SizeResult{Quality: q, Scale: 1}, buf, err
+	}
+
+	// The encoded size is roughly proportional to the pixel count
+	scale := math.Sqrt(float64(maxBytes)/float64(len(buf))) * 0.9
+	for {
+		if scale < opts.MinScale {
+			scale = opts.MinScale
+		}
+
+		scaled := img.ref()
+		if err := scaled.Resize(scale, scaled.HasAlpha()); err != nil {
+			scaled.Clear()
+			return SizeResult{}, nil, err
+		}
+
+		q, buf, err = searchQuality(opts.MinQuality, maxQuality, maxBytes, encoder(scaled))
+		scaled.Clear()
+
+		if err != ErrSizeUnreachable || scale <= opts.MinScale {
+			return SizeResult{Quality: q, Scale: scale}, buf, err
+		}
+
+		scale *= 0.8
+	}
+}
+
+// searchQuality finds the highest quality in [lo, hi] which output fits into maxBytes.
+// The smallest output is returned with ErrSizeUnreachable if none fits.
+func searchQuality(lo, hi, maxBytes int, encode func(int) ([]byte, error)) (int, []byte, error) {
+	buf, err := encode(hi)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(buf) <= maxBytes {
+		return hi, buf, nil
+	}
+
+	best, bestBuf := 0, []byte(nil)
+	smallest, smallestBuf := hi, buf
+
+	hi--
+	for lo <= hi {
+		mid := (lo + hi) / 2
+
+		if buf, err = encode(mid); err != nil {
+			return 0, nil, err
+		}
+
+		if len(buf) <= maxBytes {
+			best, bestBuf = mid, buf
+			lo = mid + 1
+		} else {
+			if len(buf) < len(smallestBuf) {
+				smallest, smallestBuf = mid, buf
+			}
+			hi = mid - 1
+		}
+	}
+
+	if bestBuf == nil {
+		return smallest, smallestBuf, ErrSizeUnreachable
+	}
+
+	return best, bestBuf, nil
+}
+
+// ref returns a new reference to the same image, so it can be transformed without touching the original
+func (img *VipsImage) ref() *VipsImage {
+	C.g_object_ref(C.gpointer(img.img))
+	return &VipsImage{img.img}
+}
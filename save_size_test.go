@@ -0,0 +1,153 @@
+package libvips_go
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSearchQuality(t *testing.T) {
+	// The output size grows linearly with quality: 10 bytes per quality point
+	encode := func(q int) ([]byte, error) {
+		return make([]byte, q*10), nil
+	}
+
+	tests := []struct {
+		name     string
+		lo, hi   int
+		maxBytes int
+		want     int
+		wantErr  error
+	}{
+		{"FitsAtMax", 10, 95, 2000, 95, nil},
+		{"ExactBudget", 10, 95, 500, 50, nil},
+		{"BetweenSteps", 10, 95, 734, 73, nil},
+		{"FitsAtMin", 10, 95, 100, 10, nil},
+		{"Unreachable", 10, 95, 99, 10, ErrSizeUnreachable},
+		{"SingleQuality", 80, 80, 500, 80, ErrSizeUnreachable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, buf, err := searchQuality(tt.lo, tt.hi, tt.maxBytes, encode)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("searchQuality() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("searchQuality() got = %d, want %d", got, tt.want)
+			}
+			if len(buf) != got*10 {
+				t.Errorf("searchQuality() returned %d bytes for quality %d", len(buf), got)
+			}
+		})
+	}
+}
+
+func TestSearchQuality_EncodeError(t *testing.T) {
+	errEncode := errors.New("encode failed")
+
+	_, _, err := searchQuality(10, 95, 100, func(q int) ([]byte, error) {
+		if q < 95 {
+			return nil, errEncode
+		}
+		return make([]byte, 1000), nil
+	})
+	if !errors.Is(err, errEncode) {
+		t.Errorf("searchQuality() error = %v, want %v", err, errEncode)
+	}
+}
+
+func TestVipsImage_SaveToSize_InvalidInput(t *testing.T) {
+	// The input is checked before the image is touched
+	img := &VipsImage{}
+
+	tests := []struct {
+		name     string
+		imgType  ImageFormat
+		maxBytes int
+		opts     SizeOptions
+	}{
+		{"Format", PNG, 1000, DefaultSizeOptions},
+		{"MaxBytes", JPEG, 0, DefaultSizeOptions},
+		{"MinQuality", JPEG, 1000, SizeOptions{Encode: DefaultEncodeConfig}},
+		{"MinQualityOverMax", JPEG, 1000, SizeOptions{Encode: DefaultEncodeConfig, MinQuality: 96}},
+		{"ZeroMinScale", JPEG, 1000, SizeOptions{Encode: DefaultEncodeConfig, MinQuality: 10, Downscale: true}},
+		{"NegativeMinScale", JPEG, 1000,
+			SizeOptions{Encode: DefaultEncodeConfig, MinQuality: 10, Downscale: true, MinScale: -1}},
+		{"MinScaleOverOne", JPEG, 1000,
+			SizeOptions{Encode: DefaultEncodeConfig, MinQuality: 10, Downscale: true, MinScale: 1.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := img.SaveToSize(tt.imgType, tt.maxBytes, tt.opts); err == nil {
+				t.Error("SaveToSize() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestVipsImage_SaveToSize(t *testing.T) {
+	full, err := loadFixture(t, "wiki_a4.png").Save(JPEG, DefaultSizeOptions.Encode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	downscale := DefaultSizeOptions
+	downscale.Downscale = true
+	downscale.MinScale = 0.05
+
+	tests := []struct {
+		name      string
+		maxBytes  int
+		opts      SizeOptions
+		wantErr   error
+		wantMaxQ  bool
+		wantScale bool
+	}{
+		{"Fits", len(full), DefaultSizeOptions, nil, true, false},
+		{"LowerQuality", len(full) - 1, DefaultSizeOptions, nil, false, false},
+		{"Unreachable", 4000, DefaultSizeOptions, ErrSizeUnreachable, false, false},
+		{"Downscaled", 4000, downscale, nil, false, true},
+	}
+	// The image is reused, SaveToSize leaves it decoded in memory
+	img := loadFixture(t, "wiki_a4.png")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, buf, err := img.SaveToSize(JPEG, tt.maxBytes, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SaveToSize() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err == nil && len(buf) > tt.maxBytes {
+				t.Errorf("SaveToSize() wrote %d bytes, want at most %d", len(buf), tt.maxBytes)
+			}
+
+			maxQ := int(tt.opts.Encode.quality)
+			if res.Quality < tt.opts.MinQuality || res.Quality > maxQ || (res.Quality == maxQ) != tt.wantMaxQ {
+				t.Errorf("SaveToSize() quality = %d, want max quality %v", res.Quality, tt.wantMaxQ)
+			}
+
+			if (res.Scale < 1) != tt.wantScale {
+				t.Errorf("SaveToSize() scale = %g, want downscale %v", res.Scale, tt.wantScale)
+			}
+
+			out, err := Load(buf)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			defer out.Clear()
+
+			if want := float64(img.Width()) * res.Scale; math.Abs(float64(out.Width())-want) > 1 {
+				t.Errorf("SaveToSize() width = %d, want %g", out.Width(), want)
+			}
+		})
+	}
+
+	if img.Width() != 1200 || img.Height() != 1800 {
+		t.Errorf("SaveToSize() resized the image to %dx%d", img.Width(), img.Height())
+	}
+
+	if _, err = img.Save(PNG, DefaultEncodeConfig); err != nil {
+		t.Errorf("Save() after SaveToSize() error = %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// Source is an encoded image which libvips reads lazily, either from a file or from an io.Reader.
+// The underlying reader must stay valid until every image loaded from the source is cleared.
+type Source struct {
+	src    *C.VipsSource
+	reader *sourceReader
+	// size of the file source, the size of a reader isn't known in advance
+	size int64
+}
+
+type sourceReader struct {
+	r   io.Reader
+	err error
+	// read counts the bytes for the limit, a zero limit is not checked
+	read, limit int64
+}
+
+func NewSource(r io.Reader) *Source {
+	reader := &sourceReader{r: r}
+
+	return &Source{
+		src:    C.vips_source_custom_new_go(C.uintptr_t(cgo.NewHandle(reader))),
+		reader: reader,
+	}
+}
+
+func NewSourceFromFile(file string) (*Source, error) {
+	stat, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	cFile := C.CString(file)
+	defer C.free(unsafe.Pointer(cFile))
+
+	src := C.vips_source_new_from_file(cFile)
+	if src == nil {
+		return nil, vipsError("NewSourceFromFile")
+	}
+
+	return &Source{src: src, size: stat.Size()}, nil
+}
+
+// Clear drops the reference to the source. Images already loaded from it keep their own reference.
+func (s *Source) Clear() {
+	if s.src != nil {
+		C.g_object_unref(C.gpointer(s.src))
+		s.src = nil
+	}
+}
+
+func (s *Source) readErr() error {
+	if s.reader != nil && s.reader.err != nil {
+		return s.reader.err
+	}
+
+	return nil
+}
+
+func LoadSource(s *Source) (*VipsImage, error) {
+	return LoadSourceWithOptions(s, LoadOptions{})
+}
+
+// LoadSourceWithOptions is LoadWithOptions for a source. MaxInputBytes of a reader source is checked
+// while it's read. Width and Height fitting needs the whole document and isn't supported.
+func LoadSourceWithOptions(s *Source, opts LoadOptions) (*VipsImage, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	if opts.Width > 0 || opts.Height > 0 {
+		return nil, fmt.Errorf("width and height fitting is not supported for sources")
+	}
+
+	if opts.MaxInputBytes > 0 {
+		if s.size > int64(opts.MaxInputBytes) {
+			return nil, &ImageTooLargeError{"input bytes", int(s.size), opts.MaxInputBytes}
+		}
+
+		if s.reader != nil {
+			s.reader.limit = int64(opts.MaxInputBytes)
+		}
+	}
+
+	var data *C.uchar
+
+	n := C.vips_source_sniff_at_most(s.src, &data, magicNumberSniffLen)
+	if n <= 0 {
+		if err := s.readErr(); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrUnsupportedImageFormat
+	}
+
+	imgType := FormatByMagicNumber(C.GoBytes(unsafe.Pointer(data), C.int(n)))
+
+	switch {
+	case imgType == Unknown:
+		return nil, ErrUnsupportedImageFormat
+	case imgType == ICO || imgType == BMP && !operationAvailable("magickload_source"):
+		// The formats decoded in Go need the whole source. The mapped memory is owned by the source, it's
+		// read in place and the decoded image doesn't refer to it.
+		size := C.size_t(0)
+
+		buf := C.vips_source_map(s.src, &size)
+		if buf == nil {
+			if err := s.readErr(); err != nil {
+				C.vips_error_clear()
+				return nil, err
+			}
+
+			return nil, vipsError("LoadSource")
+		}
+
+		return LoadWithOptions(unsafe.Slice((*byte)(buf), int(size)), opts)
+	}
+
+	img := &VipsImage{}
+	params := opts.params()
+
+	if C.vips_image_load_source_go(s.src, &params, &img.img) != 0 {
+		if err := s.readErr(); err != nil {
+			C.vips_error_clear()
+			return nil, err
+		}
+
+		return nil, vipsError("LoadSource")
+	}
+
+	return opts.finish(img)
+}
+
+// LoadReader decodes an image streamed from r without buffering the whole input in memory.
+// If r also implements io.Seeker, libvips may seek in it.
+func LoadReader(r io.Reader) (*VipsImage, error) {
+	return LoadReaderWithOptions(r, LoadOptions{})
+}
+
+func LoadReaderWithOptions(r io.Reader, opts LoadOptions) (*VipsImage, error) {
+	src := NewSource(r)
+	defer src.Clear()
+
+	return LoadSourceWithOptions(src, opts)
+}
+
+//export goSourceRead
+func goSourceRead(handle C.uintptr_t, buffer unsafe.Pointer, length C.gint64) C.gint64 {
+	reader := cgo.Handle(handle).Value().(*sourceReader)
+
+	n, err := io.ReadAtLeast(reader.r, unsafe.Slice((*byte)(buffer), int(length)), 1)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		reader.err = err
+		return -1
+	}
+
+	reader.read += int64(n)
+	if reader.limit > 0 && reader.read > reader.limit {
+		reader.err = &ImageTooLargeError{"input bytes", int(reader.read), int(reader.limit)}
+		return -1
+	}
+
+	return C.gint64(n)
+}
+
+//export goSourceSeek
+func goSourceSeek(handle C.uintptr_t, offset C.gint64, whence C.int) C.gint64 {
+	reader := cgo.Handle(handle).Value().(*sourceReader)
+
+	seeker, ok := reader.r.(io.Seeker)
+	if !ok {
+		return -1
+	}
+
+	pos, err := seeker.Seek(int64(offset), int(whence))
+	if err != nil {
+		return -1
+	}
+
+	return C.gint64(pos)
+}
+
+//export goHandleRelease
+func goHandleRelease(handle C.uintptr_t) {
+	cgo.Handle(handle).Delete()
+}
@@ -0,0 +1,81 @@
+package libvips_go
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+)
+
+type onlyReader struct{ r io.Reader }
+
+func (r onlyReader) Read(p []byte) (int, error) { return r.r.Read(p) }
+
+type failingReader struct{ err error }
+
+func (r failingReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestLoadReader(t *testing.T) {
+	startVips(t)
+
+	tests := []struct {
+		name    string
+		fixture string
+		buf     []byte
+		seek    bool
+		width   int
+		height  int
+	}{
+		{"PNG seekable", "blank.png", nil, true, 3, 2},
+		{"PNG stream", "blank.png", nil, false, 3, 2},
+		{"JPEG stream", "blank.jpeg", nil, false, 1, 1},
+		{"GIF stream", "blank.gif", nil, false, 1, 1},
+		{"ICO stream", "blank.ico", nil, false, 1, 1},
+		{"PNG ICO stream", "", wrapIco(readFixture(t, "blank.png"), 3, 2), false, 3, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := tt.buf
+			if buf == nil {
+				buf = readFixture(t, tt.fixture)
+			}
+
+			var r io.Reader = bytes.NewReader(buf)
+			if !tt.seek {
+				r = onlyReader{r}
+			}
+
+			img, err := LoadReader(r)
+			if err != nil {
+				t.Fatalf("LoadReader() error = %v", err)
+			}
+			defer img.Clear()
+
+			if img.Width() != tt.width || img.Height() != tt.height {
+				t.Errorf("LoadReader() size = %dx%d, want %dx%d", img.Width(), img.Height(), tt.width, tt.height)
+			}
+
+			// The pixels are read from the source only now, the source is gone by then
+			runtime.GC()
+
+			if _, err = img.Save(PNG, DefaultEncodeConfig); err != nil {
+				t.Errorf("Save() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadReader_Error(t *testing.T) {
+	startVips(t)
+
+	readErr := errors.New("read failed")
+
+	if _, err := LoadReader(failingReader{readErr}); !errors.Is(err, readErr) {
+		t.Errorf("LoadReader() error = %v, want %v", err, readErr)
+	}
+
+	if _, err := LoadReader(bytes.NewReader([]byte("not an image"))); !errors.Is(err, ErrUnsupportedImageFormat) {
+		t.Errorf("LoadReader() error = %v, want %v", err, ErrUnsupportedImageFormat)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"io"
+	"runtime/cgo"
+	"unsafe"
+)
+
+type targetWriter struct {
+	w   io.Writer
+	err error
+}
+
+// SaveTo encodes the image straight into w, so the whole encoded output is never held in memory
+// for the formats libvips can stream. Savers without target support are buffered and then copied.
+func (img *VipsImage) SaveTo(w io.Writer, imgType ImageFormat, opts encodeConfig) error {
+	if imgType == ICO || imgType == BMP && !operationAvailable("magicksave_buffer") {
+		b, err := img.Save(imgType, opts)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(b)
+		return err
+	}
+
+	writer := &targetWriter{w: w}
+
+	target := C.vips_target_custom_new_go(C.uintptr_t(cgo.NewHandle(writer)))
+	defer C.g_object_unref(C.gpointer(target))
+
+	err := C.int(0)
+
+	switch imgType {
+	case JPEG:
+		err = C.vips_jpegsave_target_go(img.img, target, opts.quality, opts.strip, opts.interlace)
+	case PNG:
+		err = C.vips_pngsave_target_go(img.img, target, opts.compression, opts.strip, opts.interlace, opts.palette)
+	case WEBP:
+		err = C.vips_webpsave_target_go(img.img, target, opts.quality, opts.strip, opts.lossless)
+	case GIF:
+		err = C.vips_gifsave_target_go(img.img, target)
+	case TIFF:
+		err = C.vips_tiffsave_target_go(img.img, target, opts.quality)
+	case AVIF:
+		err = C.vips_avifsave_target_go(img.img, target, opts.quality)
+	case HEIF:
+		err = C.vips_heifsave_target_go(img.img, target, opts.quality, opts.heifCompression, opts.lossless)
+	case JXL:
+		err = jxlOptions(opts).save(img.img, target, nil, nil)
+	case JP2K:
+		err = jp2kOptions(opts).save(img.img, target, nil, nil)
+	case BMP:
+		err = C.vips_bmpsave_target_go(img.img, target)
+	case PDF:
+		err = C.vips_pdfsave_target_go(img.img, target)
+	default:
+		return ErrUnsupportedImageFormat
+	}
+	if err != 0 {
+		if writer.err != nil {
+			C.vips_error_clear()
+			return writer.err
+		}
+
+		return vipsError("SaveTo")
+	}
+
+	return nil
+}
+
+//export goTargetWrite
+func goTargetWrite(handle C.uintptr_t, data unsafe.Pointer, length C.gint64) C.gint64 {
+	writer := cgo.Handle(handle).Value().(*targetWriter)
+	if writer.err != nil {
+		return -1
+	}
+
+	n, err := writer.w.Write(unsafe.Slice((*byte)(data), int(length)))
+	if err != nil {
+		writer.err = err
+		return -1
+	}
+
+	return C.gint64(n)
+}
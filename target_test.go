@@ -0,0 +1,57 @@
+package libvips_go
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write([]byte) (int, error) { return 0, w.err }
+
+func TestVipsImage_SaveTo(t *testing.T) {
+	img := loadFixture(t, "blank.png")
+
+	tests := []struct {
+		name    string
+		imgType ImageFormat
+	}{
+		{"PNG", PNG},
+		{"JPEG", JPEG},
+		{"GIF", GIF},
+		{"ICO", ICO},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			if err := img.SaveTo(buf, tt.imgType, DefaultEncodeConfig); err != nil {
+				t.Fatalf("SaveTo() error = %v", err)
+			}
+
+			if got := FormatByMagicNumber(buf.Bytes()); got != tt.imgType {
+				t.Errorf("SaveTo() wrote %s, want %s", got, tt.imgType)
+			}
+
+			out, err := Load(buf.Bytes())
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			defer out.Clear()
+
+			if out.Width() != img.Width() || out.Height() != img.Height() {
+				t.Errorf("SaveTo() size = %dx%d, want %dx%d", out.Width(), out.Height(), img.Width(), img.Height())
+			}
+		})
+	}
+}
+
+func TestVipsImage_SaveTo_WriteError(t *testing.T) {
+	img := loadFixture(t, "blank.png")
+
+	writeErr := errors.New("write failed")
+
+	if err := img.SaveTo(failingWriter{writeErr}, PNG, DefaultEncodeConfig); !errors.Is(err, writeErr) {
+		t.Errorf("SaveTo() error = %v, want %v", err, writeErr)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+MIT License
+
+Copyright (c) 2021 MyBack
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package libvips_go
+
+/*
+#cgo pkg-config: vips
+#cgo LDFLAGS: -s -w
+#cgo CFLAGS: -O3
+#include "vips.h"
+*/
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+type Size int
+type Interesting int
+type Intent int
+
+const (
+	SizeBoth  = Size(C.VIPS_SIZE_BOTH)
+	SizeUp    = Size(C.VIPS_SIZE_UP)
+	SizeDown  = Size(C.VIPS_SIZE_DOWN)
+	SizeForce = Size(C.VIPS_SIZE_FORCE)
+
+	InterestingNone      = Interesting(C.VIPS_INTERESTING_NONE)
+	InterestingCentre    = Interesting(C.VIPS_INTERESTING_CENTRE)
+	InterestingEntropy   = Interesting(C.VIPS_INTERESTING_ENTROPY)
+	InterestingAttention = Interesting(C.VIPS_INTERESTING_ATTENTION)
+	InterestingLow       = Interesting(C.VIPS_INTERESTING_LOW)
+	InterestingHigh      = Interesting(C.VIPS_INTERESTING_HIGH)
+	InterestingAll       = Interesting(C.VIPS_INTERESTING_ALL)
+)
+
+// The zero Intent keeps the libvips default, which is relative
+const (
+	IntentDefault Intent = iota
+	IntentPerceptual
+	IntentRelative
+	IntentSaturation
+	IntentAbsolute
+)
+
+func (i Intent) vipsIntent() C.VipsIntent {
+	switch i {
+	case IntentPerceptual:
+		return C.VIPS_INTENT_PERCEPTUAL
+	case IntentSaturation:
+		return C.VIPS_INTENT_SATURATION
+	case IntentAbsolute:
+		return C.VIPS_INTENT_ABSOLUTE
+	default:
+		return C.VIPS_INTENT_RELATIVE
+	}
+}
+
+type ThumbnailOptions struct {
+	// Size limits the direction of scaling: both ways, up-only, down-only or force to exact dimensions
+	Size Size
+	// Crop fills the whole box and cuts away the overflow, picking the area with the given strategy
+	Crop Interesting
+	// Linear makes the shrink in linear light. It is slower, but gives better results for some images
+	Linear bool
+	// NoRotate disables the automatic EXIF orientation correction
+	NoRotate bool
+	// ImportProfile is the fallback ICC profile for images without an embedded one
+	ImportProfile string
+	// ExportProfile converts the thumbnail to this ICC profile. The output stays in sRGB if empty
+	ExportProfile string
+	// Intent is the rendering intent of the profile conversions
+	Intent Intent
+	// Load limits the input like LoadWithOptions, the header is checked before the image is shrunk.
+	// Only the limits and FailOn are used.
+	Load LoadOptions
+}
+
+// Thumbnail decodes buf and scales it to fit into width x height in one step, so JPEG, WebP, HEIF and PDF
+// loaders can shrink on load instead of decoding the full resolution image. A zero width or height leaves
+// that dimension unconstrained.
+func Thumbnail(buf []byte, width, height int, opts ThumbnailOptions) (*VipsImage, error) {
+	if width < 0 || height < 0 || (width == 0 && height == 0) {
+		return nil, fmt.Errorf("dimensions must be a positive values")
+	}
+
+	if opts.Intent < IntentDefault || opts.Intent > IntentAbsolute {
+		return nil, fmt.Errorf("invalid intent value %d", opts.Intent)
+	}
+
+	if FormatByMagicNumber(buf) == Unknown {
+		return nil, ErrUnsupportedImageFormat
+	}
+
+	if limits := opts.Load.limits(); limits != (LoadOptions{}) {
+		header, err := LoadWithOptions(buf, limits)
+		if err != nil {
+			return nil, err
+		}
+
+		header.Clear()
+	}
+
+	if width == 0 {
+		width = C.VIPS_MAX_COORD
+	}
+
+	if height == 0 {
+		height = C.VIPS_MAX_COORD
+	}
+
+	var importProfile, exportProfile *C.char
+	if opts.ImportProfile != "" {
+		importProfile = C.CString(opts.ImportProfile)
+		defer C.free(unsafe.Pointer(importProfile))
+	}
+
+	if opts.ExportProfile != "" {
+		exportProfile = C.CString(opts.ExportProfile)
+		defer C.free(unsafe.Pointer(exportProfile))
+	}
+
+	img := &VipsImage{}
+
+	if C.vips_thumbnail_buffer_go(unsafe.Pointer(&buf[0]), C.size_t(len(buf)), &img.img, C.int(width), C.int(height),
+		C.VipsSize(opts.Size), C.VipsInteresting(opts.Crop), gbool(opts.Linear), gbool(opts.NoRotate),
+		importProfile, exportProfile, opts.Intent.vipsIntent(), C.int(opts.Load.FailOn)) != 0 {
+		return nil, vipsError("Thumbnail")
+	}
+
+	return img, nil
+}
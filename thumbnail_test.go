@@ -0,0 +1,83 @@
+package libvips_go
+
+import "testing"
+
+func TestIntent_vipsIntent(t *testing.T) {
+	// The values of VipsIntent
+	tests := []struct {
+		name   string
+		intent Intent
+		want   int
+	}{
+		{"Default is relative", IntentDefault, 1},
+		{"Perceptual", IntentPerceptual, 0},
+		{"Relative", IntentRelative, 1},
+		{"Saturation", IntentSaturation, 2},
+		{"Absolute", IntentAbsolute, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := int(tt.intent.vipsIntent()); got != tt.want {
+				t.Errorf("vipsIntent() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	startVips(t)
+
+	a4 := readFixture(t, "wiki_a4.png")
+	blank := readFixture(t, "blank.png")
+
+	tests := []struct {
+		name          string
+		buf           []byte
+		width, height int
+		opts          ThumbnailOptions
+		wantW, wantH  int
+	}{
+		{"Fit box", a4, 150, 300, ThumbnailOptions{}, 150, 225},
+		{"Width only", a4, 600, 0, ThumbnailOptions{}, 600, 900},
+		{"Height only", a4, 0, 900, ThumbnailOptions{}, 600, 900},
+		{"Crop", a4, 100, 100, ThumbnailOptions{Crop: InterestingCentre}, 100, 100},
+		{"Force", a4, 100, 100, ThumbnailOptions{Size: SizeForce}, 100, 100},
+		{"Down only", blank, 30, 20, ThumbnailOptions{Size: SizeDown}, 3, 2},
+		{"Up", blank, 30, 20, ThumbnailOptions{}, 30, 20},
+		{"Intent", a4, 150, 300, ThumbnailOptions{Intent: IntentPerceptual}, 150, 225},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Thumbnail(tt.buf, tt.width, tt.height, tt.opts)
+			if err != nil {
+				t.Fatalf("Thumbnail() error = %v", err)
+			}
+			defer img.Clear()
+
+			if img.Width() != tt.wantW || img.Height() != tt.wantH {
+				t.Errorf("Thumbnail() size = %dx%d, want %dx%d", img.Width(), img.Height(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestThumbnail_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name          string
+		buf           []byte
+		width, height int
+		opts          ThumbnailOptions
+	}{
+		{"No dimensions", []byte("\x89PNG\r\n\x1a\n"), 0, 0, ThumbnailOptions{}},
+		{"Negative width", []byte("\x89PNG\r\n\x1a\n"), -1, 10, ThumbnailOptions{}},
+		{"Unknown format", []byte("not an image"), 10, 10, ThumbnailOptions{}},
+		{"Invalid intent", []byte("\x89PNG\r\n\x1a\n"), 10, 10, ThumbnailOptions{Intent: Intent(42)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Thumbnail(tt.buf, tt.width, tt.height, tt.opts); err == nil {
+				t.Error("Thumbnail() error = nil, want error")
+			}
+		})
+	}
+}
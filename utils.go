@@ -34,7 +34,6 @@ import "C"
 import (
 	"fmt"
 	"image"
-	"io/ioutil"
 	"unsafe"
 )
 
@@ -58,7 +57,7 @@ func (ec *encodeConfig) Compression(i int) {
 }
 
 func (ec *encodeConfig) HEIFCompression(i HEIFCompressionType) {
-	ec.compression = C.int(i)
+	ec.heifCompression = C.int(i)
 }
 
 func (ec *encodeConfig) Interlace(b bool) {
@@ -95,49 +94,154 @@ func gbool(b bool) C.gboolean {
 	return C.gboolean(0)
 }
 
-func vipsError() error {
-	defer C.vips_error_clear()
-	return fmt.Errorf(C.GoString(C.vips_error_buffer()))
+func Load(buf []byte) (*VipsImage, error) {
+	return LoadWithOptions(buf, LoadOptions{})
 }
 
-func Load(buf []byte) (*VipsImage, error) {
+func LoadWithOptions(buf []byte, opts LoadOptions) (*VipsImage, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := opts.checkInput(buf); err != nil {
+		return nil, err
+	}
+
 	imgType := FormatByMagicNumber(buf)
 	if imgType == Unknown {
 		return nil, ErrUnsupportedImageFormat
 	}
 
-	img := &VipsImage{}
+	var img *VipsImage
 
-	err := C.int(0)
+	switch {
+	case imgType == ICO:
+		var err error
+		if img, err = loadIco(buf, 0, opts); err != nil {
+			return nil, err
+		}
+	case imgType == BMP && !operationAvailable("magickload_buffer"):
+		var err error
+		if img, err = loadBMP(buf, opts); err != nil {
+			return nil, err
+		}
+	default:
+		params := opts.params()
 
-	if err = C.vips_image_load_go(unsafe.Pointer(&buf[0]), C.size_t(len(buf)), C.int(imgType), &img.img); err != 0 {
-		return nil, vipsError()
+		// The header of the document at scale 1 gives its size, nothing is rendered yet
+		if (imgType == SVG || imgType == PDF) && (opts.Width > 0 || opts.Height > 0) {
+			params.scale = 1
+
+			doc, err := loadBuffer(buf, imgType, &params)
+			if err != nil {
+				return nil, err
+			}
+
+			params.scale = C.double(opts.fitScale(doc.Width(), doc.Height()))
+			doc.Clear()
+		}
+
+		var err error
+		if img, err = loadBuffer(buf, imgType, &params); err != nil {
+			return nil, err
+		}
+	}
+
+	return opts.finish(img)
+}
+
+func loadBuffer(buf []byte, imgType ImageFormat, params *C.LoadParams) (*VipsImage, error) {
+	img := &VipsImage{}
+
+	if C.vips_image_load_go(unsafe.Pointer(&buf[0]), C.size_t(len(buf)), C.int(imgType), params, &img.img) != 0 {
+		return nil, vipsError("LoadWithOptions")
 	}
 
 	return img, nil
 }
 
 func LoadFromFile(file string) (*VipsImage, error) {
-	b, err := ioutil.ReadFile(file)
+	return LoadFromFileWithOptions(file, LoadOptions{})
+}
+
+func LoadFromFileWithOptions(file string, opts LoadOptions) (*VipsImage, error) {
+	src, err := NewSourceFromFile(file)
 	if err != nil {
 		return nil, err
 	}
+	defer src.Clear()
 
-	return Load(b)
+	return LoadSourceWithOptions(src, opts)
 }
 
 func LoadPDFPages(buf []byte, page, num int) (*VipsImage, error) {
+	return LoadPDFPagesWithOptions(buf, page, num, LoadOptions{})
+}
+
+// LoadPDFPagesWithOptions loads num pages from page as one tall image. Width and Height of opts fit
+// every page into the box.
+func LoadPDFPagesWithOptions(buf []byte, page, num int, opts LoadOptions) (*VipsImage, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := opts.checkInput(buf); err != nil {
+		return nil, err
+	}
+
 	imgType := FormatByMagicNumber(buf)
 	if imgType != PDF {
 		return nil, ErrInvalidFileFormat
 	}
 
+	params := opts.params()
+
+	if opts.Width > 0 || opts.Height > 0 {
+		params.scale = 1
+
+		doc, err := loadPDFPages(buf, page, 1, &params)
+		if err != nil {
+			return nil, err
+		}
+
+		params.scale = C.double(opts.fitScale(doc.Width(), doc.Height()))
+		doc.Clear()
+	}
+
+	img, err := loadPDFPages(buf, page, num, &params)
+	if err != nil {
+		return nil, err
+	}
+
+	return opts.finish(img)
+}
+
+func loadPDFPages(buf []byte, page, num int, params *C.LoadParams) (*VipsImage, error) {
 	img := &VipsImage{}
 
-	err := C.int(0)
+	if C.vips_pdf_load_go(unsafe.Pointer(&buf[0]), C.size_t(len(buf)), &img.img, C.int(page), C.int(num), params) != 0 {
+		return nil, vipsError("LoadPDFPages")
+	}
+
+	return img, nil
+}
+
+// LoadJP2KPage loads a resolution level of JPEG 2000 image. Level 0 is the full image, every next one
+// halves the dimensions.
+func LoadJP2KPage(buf []byte, page int) (*VipsImage, error) {
+	imgType := FormatByMagicNumber(buf)
+	if imgType != JP2K {
+		return nil, ErrInvalidFileFormat
+	}
+
+	if page < 0 {
+		return nil, fmt.Errorf("invalid page value %d", page)
+	}
+
+	img := &VipsImage{}
 
-	if err = C.vips_pdf_load_go(unsafe.Pointer(&buf[0]), C.size_t(len(buf)), &img.img, C.int(page), C.int(num)); err != 0 {
-		return nil, vipsError()
+	if C.vips_jp2k_load_go(unsafe.Pointer(&buf[0]), C.size_t(len(buf)), &img.img, C.int(page)) != 0 {
+		return nil, vipsError("LoadJP2KPage")
 	}
 
 	return img, nil
@@ -169,7 +273,7 @@ func Join(in []*VipsImage) (*VipsImage, error) {
 	}
 
 	if C.vips_arrayjoin_go(&arr[0], &tmp, C.int(len(arr))) != 0 {
-		return nil, vipsError()
+		return nil, vipsError("Join")
 	}
 
 	return &VipsImage{tmp}, nil
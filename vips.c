@@ -23,31 +23,90 @@ SOFTWARE.
 */
 
 #include "vips.h"
+#include "_cgo_export.h"
 #include <string.h>
 
 int vips_initialize_go() {
     return vips_init("libvips-go");
 }
 
-int vips_image_load_go(void *buf, size_t len, int imgFmt, VipsImage **out) {
+int vips_operation_block_go(const char *name) {
+#if VIPS_VERSION_AT_LEAST(8, 13)
+    vips_operation_block_set(name, TRUE);
+    return 0;
+#else
+    vips_error("vips_operation_block", "Blocking operations requires libvips 8.13+");
+    return 1;
+#endif
+}
+
+// libvips 8.12 replaced the boolean "fail" flag with "fail_on" and added "unlimited" to the loaders that
+// limit their input. On older versions "unlimited" is ignored by repeating the access argument instead.
+#if VIPS_VERSION_AT_LEAST(8, 12)
+#define LOAD_FAIL_ON(params) "fail_on", (params)->fail_on
+#define LOAD_UNLIMITED(params) "unlimited", (params)->unlimited
+#else
+#define LOAD_FAIL_ON(params) "fail", (params)->fail_on != 0
+#define LOAD_UNLIMITED(params) "access", VIPS_ACCESS_SEQUENTIAL
+#endif
+
+gboolean vips_operation_available_go(const char *name) {
+    return vips_type_find("VipsOperation", name) != 0;
+}
+
+// Optional modules may be missing in the linked libvips. The error is the one of the operation lookup,
+// so it's recognized as an unavailable loader or saver.
+static gboolean vips_operation_exists_go(const char *name) {
+    if (vips_type_find("VipsOperation", name))
+        return TRUE;
+
+    vips_error("VipsOperation", "class \"%s\" not found", name);
+    return FALSE;
+}
+
+int vips_image_load_go(void *buf, size_t len, int imgFmt, LoadParams *params, VipsImage **out) {
 	if (imgFmt == JPEG) {
-		return vips_jpegload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, NULL);
+		return vips_jpegload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, LOAD_FAIL_ON(params), NULL);
 	} else if (imgFmt == PNG) {
-		return vips_pngload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, NULL);
+		return vips_pngload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, LOAD_FAIL_ON(params),
+		    LOAD_UNLIMITED(params), NULL);
 	} else if (imgFmt == WEBP) {
-		return vips_webpload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, NULL);
+		return vips_webpload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, LOAD_FAIL_ON(params), NULL);
 	} else if (imgFmt == GIF) {
-        return vips_gifload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, NULL);
+        return vips_gifload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, LOAD_FAIL_ON(params), NULL);
     } else if (imgFmt == PDF) {
-    	return vips_pdfload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, NULL);
+    	return vips_pdfload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, LOAD_FAIL_ON(params),
+    	    "dpi", params->dpi, "scale", params->scale, NULL);
 	} else if (imgFmt == BMP) {
-	    return vips_magickload_buffer(buf, len, out, NULL);
+	    return vips_magickload_buffer(buf, len, out, LOAD_FAIL_ON(params), NULL);
 	} else if (imgFmt == TIFF) {
-    	return vips_tiffload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, NULL);
+    	return vips_tiffload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, LOAD_FAIL_ON(params), NULL);
 	} else if (imgFmt == HEIF || imgFmt == AVIF) {
-	    return vips_heifload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, NULL);
+	    return vips_heifload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, LOAD_FAIL_ON(params),
+	        LOAD_UNLIMITED(params), NULL);
 	} else if (imgFmt == SVG) {
-        return vips_svgload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, NULL);
+        return vips_svgload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, LOAD_FAIL_ON(params),
+            LOAD_UNLIMITED(params), "dpi", params->dpi, "scale", params->scale, NULL);
+    } else if (imgFmt == JXL) {
+#if VIPS_VERSION_AT_LEAST(8, 11)
+        if (!vips_operation_exists_go("jxlload_buffer"))
+            return 1;
+
+        return vips_jxlload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, LOAD_FAIL_ON(params), NULL);
+#else
+        vips_operation_exists_go("jxlload_buffer");
+        return 1;
+#endif
+    } else if (imgFmt == JP2K) {
+#if VIPS_VERSION_AT_LEAST(8, 11)
+        if (!vips_operation_exists_go("jp2kload_buffer"))
+            return 1;
+
+        return vips_jp2kload_buffer(buf, len, out, "access", VIPS_ACCESS_SEQUENTIAL, LOAD_FAIL_ON(params), NULL);
+#else
+        vips_operation_exists_go("jp2kload_buffer");
+        return 1;
+#endif
     } else {
         vips_error("vips_image_load", "Unsupported image format");
         return 1;
@@ -56,7 +115,7 @@ int vips_image_load_go(void *buf, size_t len, int imgFmt, VipsImage **out) {
     return 0;
 }
 
-int vips_pdf_load_go(void *buf, size_t len, VipsImage **out, int page, int n) {
+int vips_pdf_load_go(void *buf, size_t len, VipsImage **out, int page, int n, LoadParams *params) {
     if (page < 1) {
         page = 0;
     }
@@ -65,13 +124,136 @@ int vips_pdf_load_go(void *buf, size_t len, VipsImage **out, int page, int n) {
         n = 1;
     }
 
-    return vips_pdfload_buffer(buf, len, out, "page", page, "n", n, "access", VIPS_ACCESS_SEQUENTIAL, NULL);
+    return vips_pdfload_buffer(buf, len, out, "page", page, "n", n, "access", VIPS_ACCESS_SEQUENTIAL,
+        LOAD_FAIL_ON(params), "dpi", params->dpi, "scale", params->scale, NULL);
+}
+
+int vips_pdf_load_page_go(void *buf, size_t len, VipsImage **out, int page, PdfLoadParams *p) {
+    VipsArrayDouble *background;
+    int res;
+
+#if !VIPS_VERSION_AT_LEAST(8, 14)
+    if (p->password != NULL) {
+        vips_error("vips_pdfload", "Password protected PDF requires libvips 8.14+");
+        return 1;
+    }
+#endif
+
+    background = vips_array_double_new(p->background, 4);
+
+    res = vips_pdfload_buffer(buf, len, out,
+        "page", page,
+        "dpi", p->dpi,
+        "scale", p->scale,
+        "background", background,
+        "access", VIPS_ACCESS_SEQUENTIAL,
+#if VIPS_VERSION_AT_LEAST(8, 14)
+        p->password != NULL ? "password" : NULL, p->password,
+#endif
+        NULL);
+
+    vips_area_unref((VipsArea *) background);
+
+    return res;
+}
+
+// Page of JPEG 2000 is the resolution level, every level halves the image dimensions
+int vips_jp2k_load_go(void *buf, size_t len, VipsImage **out, int page) {
+#if VIPS_VERSION_AT_LEAST(8, 11)
+    if (!vips_operation_exists_go("jp2kload_buffer"))
+        return 1;
+
+    return vips_jp2kload_buffer(buf, len, out, "page", page, "access", VIPS_ACCESS_SEQUENTIAL, NULL);
+#else
+    vips_operation_exists_go("jp2kload_buffer");
+    return 1;
+#endif
+}
+
+// Unlike the buffer loaders the source loader isn't known in advance. The "unlimited", "dpi" and "scale"
+// arguments exist only in some loaders and setting an unknown one fails the load.
+int vips_image_load_source_go(VipsSource *source, LoadParams *params, VipsImage **out) {
+    const char *loader = vips_foreign_find_load_source(source);
+    if (loader == NULL) {
+        return 1;
+    }
+
+    if (g_str_has_prefix(loader, "VipsForeignLoadPdf")) {
+        *out = vips_image_new_from_source(source, "", "access", VIPS_ACCESS_SEQUENTIAL, LOAD_FAIL_ON(params),
+            "dpi", params->dpi, "scale", params->scale, NULL);
+    } else if (g_str_has_prefix(loader, "VipsForeignLoadSvg")) {
+        *out = vips_image_new_from_source(source, "", "access", VIPS_ACCESS_SEQUENTIAL, LOAD_FAIL_ON(params),
+            LOAD_UNLIMITED(params), "dpi", params->dpi, "scale", params->scale, NULL);
+    } else if (g_str_has_prefix(loader, "VipsForeignLoadPng") || g_str_has_prefix(loader, "VipsForeignLoadHeif")) {
+        *out = vips_image_new_from_source(source, "", "access", VIPS_ACCESS_SEQUENTIAL, LOAD_FAIL_ON(params),
+            LOAD_UNLIMITED(params), NULL);
+    } else {
+        *out = vips_image_new_from_source(source, "", "access", VIPS_ACCESS_SEQUENTIAL, LOAD_FAIL_ON(params), NULL);
+    }
+
+    if (*out == NULL) {
+        return 1;
+    }
+
+    return 0;
+}
+
+static gint64 vips_source_read_go(VipsSourceCustom *source, void *buffer, gint64 length, gpointer handle) {
+    return goSourceRead((uintptr_t) handle, buffer, length);
+}
+
+static gint64 vips_source_seek_go(VipsSourceCustom *source, gint64 offset, int whence, gpointer handle) {
+    return goSourceSeek((uintptr_t) handle, offset, whence);
+}
+
+// Releases the Go side of a callback handle once libvips drops the last reference to the object
+static void vips_handle_release_go(gpointer handle, GObject *object) {
+    goHandleRelease((uintptr_t) handle);
+}
+
+VipsSource *vips_source_custom_new_go(uintptr_t handle) {
+    VipsSourceCustom *source = vips_source_custom_new();
+
+    g_signal_connect(source, "read", G_CALLBACK(vips_source_read_go), (gpointer) handle);
+    g_signal_connect(source, "seek", G_CALLBACK(vips_source_seek_go), (gpointer) handle);
+    g_object_weak_ref(G_OBJECT(source), vips_handle_release_go, (gpointer) handle);
+
+    return VIPS_SOURCE(source);
+}
+
+int vips_thumbnail_buffer_go(void *buf, size_t len, VipsImage **out, int width, int height, VipsSize size,
+                             VipsInteresting crop, gboolean linear, gboolean no_rotate, const char *import_profile,
+                             const char *export_profile, VipsIntent intent, int fail_on) {
+    // The loader of the thumbnail gets only the options which every loader has
+#if VIPS_VERSION_AT_LEAST(8, 12)
+    static const char *fail_on_options[] = {"fail_on=none", "fail_on=truncated", "fail_on=error", "fail_on=warning"};
+    const char *option_string = fail_on_options[fail_on];
+#else
+    const char *option_string = fail_on != 0 ? "fail=true" : "";
+#endif
+
+    return vips_thumbnail_buffer(buf, len, out, width,
+        "option_string", option_string,
+        "height", height,
+        "size", size,
+        "crop", crop,
+        "linear", linear,
+        "no_rotate", no_rotate,
+        "import_profile", import_profile,
+        "export_profile", export_profile,
+        "intent", intent,
+        NULL);
 }
 
 VipsImage *vips_image_new_from_bytes_go(const void *data, size_t size, int width, int height) {
     return vips_image_new_from_memory(data, size, width, height, 4, VIPS_FORMAT_UCHAR);
 }
 
+// Unlike vips_image_new_from_bytes_go the data is copied, so the caller may free it
+VipsImage *vips_image_new_from_rgba_go(const void *data, size_t size, int width, int height) {
+    return vips_image_new_from_memory_copy(data, size, width, height, 4, VIPS_FORMAT_UCHAR);
+}
+
 VipsBandFormat vips_band_format_go(VipsImage *in) {
     return in->BandFmt;
 }
@@ -91,8 +273,75 @@ void swap_and_clear_go(VipsImage **in, VipsImage *out) {
 
 gboolean vips_is_animated_go(VipsImage * in) {
     return(vips_image_get_typeof(in, "page-height") != G_TYPE_INVALID &&
-           vips_image_get_typeof(in, "gif-delay") != G_TYPE_INVALID &&
-           vips_image_get_typeof(in, "gif-loop") != G_TYPE_INVALID);
+           (vips_image_get_typeof(in, "delay") != G_TYPE_INVALID ||
+            vips_image_get_typeof(in, "gif-delay") != G_TYPE_INVALID) &&
+           (vips_image_get_typeof(in, "loop") != G_TYPE_INVALID ||
+            vips_image_get_typeof(in, "gif-loop") != G_TYPE_INVALID));
+}
+
+static void vips_image_eval_go(VipsImage *image, VipsProgress *progress, gpointer handle) {
+    // The signal is emitted on the watched image, but evaluation runs on the image at the end of the pipeline
+    if (goImageEval((uintptr_t) handle, progress)) {
+        vips_image_set_kill(progress->im, TRUE);
+    }
+}
+
+static void vips_image_forward_preeval_go(VipsImage *image, VipsProgress *progress, gpointer signal) {
+    g_signal_emit_by_name(signal, "preeval", progress);
+}
+
+static void vips_image_forward_eval_go(VipsImage *image, VipsProgress *progress, gpointer signal) {
+    g_signal_emit_by_name(signal, "eval", progress);
+}
+
+static void vips_image_forward_posteval_go(VipsImage *image, VipsProgress *progress, gpointer signal) {
+    g_signal_emit_by_name(signal, "posteval", progress);
+}
+
+// Keeps a reference to the image until the handler is disconnected, operations may replace it meanwhile.
+// An image inherits the image which gets the evaluation signals from its input. The watched image takes
+// them over, so the handler doesn't run for other evaluations of the input, and forwards them to keep
+// the progress callbacks of the input working.
+gulong vips_image_connect_eval_go(VipsImage *in, uintptr_t handle) {
+    VipsImage *signal = in->progress_signal;
+
+    g_object_ref(in);
+    in->progress_signal = in;
+
+    if (signal != NULL && signal != in) {
+        g_signal_connect(in, "preeval", G_CALLBACK(vips_image_forward_preeval_go), signal);
+        g_signal_connect(in, "eval", G_CALLBACK(vips_image_forward_eval_go), signal);
+        g_signal_connect(in, "posteval", G_CALLBACK(vips_image_forward_posteval_go), signal);
+    }
+
+    return g_signal_connect(in, "eval", G_CALLBACK(vips_image_eval_go), (gpointer) handle);
+}
+
+void vips_image_disconnect_eval_go(VipsImage *in, gulong id) {
+    g_signal_handler_disconnect(in, id);
+    g_object_unref(in);
+}
+
+static void vips_image_preeval_progress_go(VipsImage *image, VipsProgress *progress, gpointer handle) {
+    goImageProgress((uintptr_t) handle, PREEVAL, progress);
+}
+
+static void vips_image_eval_progress_go(VipsImage *image, VipsProgress *progress, gpointer handle) {
+    goImageProgress((uintptr_t) handle, EVAL, progress);
+}
+
+static void vips_image_posteval_progress_go(VipsImage *image, VipsProgress *progress, gpointer handle) {
+    goImageProgress((uintptr_t) handle, POSTEVAL, progress);
+}
+
+// The handlers live as long as the image, images derived from it report their evaluation to it too
+void vips_image_connect_progress_go(VipsImage *in, uintptr_t handle) {
+    vips_image_set_progress(in, TRUE);
+
+    g_signal_connect(in, "preeval", G_CALLBACK(vips_image_preeval_progress_go), (gpointer) handle);
+    g_signal_connect(in, "eval", G_CALLBACK(vips_image_eval_progress_go), (gpointer) handle);
+    g_signal_connect(in, "posteval", G_CALLBACK(vips_image_posteval_progress_go), (gpointer) handle);
+    g_object_weak_ref(G_OBJECT(in), vips_handle_release_go, (gpointer) handle);
 }
 
 int vips_get_orientation(VipsImage *image) {
@@ -321,11 +570,11 @@ int vips_heifsave_go(VipsImage *in, void **buf, size_t *len, int quality, int co
     return vips_heifsave_buffer(in, buf, len, "Q", quality, "compression", compression, "lossless", lossless, NULL);
 }
 
-// Used by image magic
 int vips_gifsave_go(VipsImage *in, void **buf, size_t *len) {
-    return vips_magicksave_buffer(in, buf, len, "format", "gif", NULL);
+    return vips_gifsave_params_go(in, NULL, buf, len, NULL);
 }
 
+// Used by image magic
 int vips_bmpsave_go(VipsImage *in, void **buf, size_t *len) {
     return vips_magicksave_buffer(in, buf, len, "format", "bmp", NULL);
 }
@@ -335,6 +584,413 @@ int vips_pdfsave_go(VipsImage *in, void **buf, size_t *len) {
 }
 // End
 
+static gint64 vips_target_write_go(VipsTargetCustom *target, const void *data, gint64 length, gpointer handle) {
+    return goTargetWrite((uintptr_t) handle, (void *) data, length);
+}
+
+VipsTarget *vips_target_custom_new_go(uintptr_t handle) {
+    VipsTargetCustom *target = vips_target_custom_new();
+
+    g_signal_connect(target, "write", G_CALLBACK(vips_target_write_go), (gpointer) handle);
+    g_object_weak_ref(G_OBJECT(target), vips_handle_release_go, (gpointer) handle);
+
+    return VIPS_TARGET(target);
+}
+
+// Copies an already encoded buffer to the target for savers that can't write to a target directly
+static int vips_target_write_buffer_go(VipsTarget *target, void *buf, size_t len) {
+    int res = vips_target_write(target, buf, len);
+
+#if VIPS_VERSION_AT_LEAST(8, 13)
+    if (!res) res = vips_target_end(target);
+#else
+    if (!res) vips_target_finish(target);
+#endif
+
+    g_free(buf);
+
+    return res;
+}
+
+int vips_jpegsave_target_go(VipsImage *in, VipsTarget *target, int quality, int strip, int interlace) {
+    return vips_jpegsave_target(in, target,
+        "Q", quality,
+        "strip", strip,
+        "optimize_coding", TRUE,
+        "interlace", interlace,
+        NULL);
+}
+
+int vips_pngsave_target_go(VipsImage *in, VipsTarget *target, int compression, int strip, int interlace, int palette) {
+    return vips_pngsave_target(in, target,
+        "compression", compression,
+        "strip", strip,
+        "filter", VIPS_FOREIGN_PNG_FILTER_NONE,
+        "interlace", interlace,
+        "palette", palette,
+        NULL);
+}
+
+int vips_webpsave_target_go(VipsImage *in, VipsTarget *target, int quality, int strip, gboolean lossless) {
+    return vips_webpsave_target(in, target, "strip", strip, "Q", quality, "lossless", lossless, NULL);
+}
+
+int vips_tiffsave_target_go(VipsImage *in, VipsTarget *target, int quality) {
+#if VIPS_VERSION_AT_LEAST(8, 13)
+    return vips_tiffsave_target(in, target, "Q", quality, NULL);
+#else
+    void *buf = NULL;
+    size_t len = 0;
+
+    if (vips_tiffsave_go(in, &buf, &len, quality))
+        return 1;
+
+    return vips_target_write_buffer_go(target, buf, len);
+#endif
+}
+
+int vips_avifsave_target_go(VipsImage *in, VipsTarget *target, int quality) {
+    return vips_heifsave_target(in, target, "Q", quality, "compression", VIPS_FOREIGN_HEIF_COMPRESSION_AV1, NULL);
+}
+
+int vips_heifsave_target_go(VipsImage *in, VipsTarget *target, int quality, int compression, gboolean lossless) {
+    return vips_heifsave_target(in, target, "Q", quality, "compression", compression, "lossless", lossless, NULL);
+}
+
+int vips_gifsave_target_go(VipsImage *in, VipsTarget *target) {
+    return vips_gifsave_params_go(in, target, NULL, NULL, NULL);
+}
+
+// Image magick savers can't write to a target, so the encoded buffer is copied
+int vips_bmpsave_target_go(VipsImage *in, VipsTarget *target) {
+    void *buf = NULL;
+    size_t len = 0;
+
+    if (vips_bmpsave_go(in, &buf, &len))
+        return 1;
+
+    return vips_target_write_buffer_go(target, buf, len);
+}
+
+int vips_pdfsave_target_go(VipsImage *in, VipsTarget *target) {
+    void *buf = NULL;
+    size_t len = 0;
+
+    if (vips_pdfsave_go(in, &buf, &len))
+        return 1;
+
+    return vips_target_write_buffer_go(target, buf, len);
+}
+
+// Savers with all options. The image is written to the target if it's set, to the buffer otherwise
+#define VIPS_SAVE_GO(format, in, target, buf, len, ...) \
+    ((target) != NULL ? vips_##format##save_target((in), (target), __VA_ARGS__) : \
+        vips_##format##save_buffer((in), (buf), (len), __VA_ARGS__))
+
+// Unset bitdepth lets the saver pick it by the image format. A NULL name ends the argument list,
+// so bitdepth is always passed last.
+#define VIPS_SAVE_BITDEPTH(p) ((p)->bitdepth > 0 ? "bitdepth" : NULL), (p)->bitdepth
+
+int vips_jpegsave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, JpegSaveParams *p) {
+    return VIPS_SAVE_GO(jpeg, in, target, buf, len,
+        "Q", p->quality,
+        "strip", p->strip,
+        "interlace", p->interlace,
+        "optimize_coding", p->optimize_coding,
+        "subsample_mode", p->subsample_mode,
+        "trellis_quant", p->trellis_quant,
+        "overshoot_deringing", p->overshoot_deringing,
+        "optimize_scans", p->optimize_scans,
+        "quant_table", p->quant_table,
+        NULL);
+}
+
+int vips_pngsave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, PngSaveParams *p) {
+    return VIPS_SAVE_GO(png, in, target, buf, len,
+        "compression", p->compression,
+        "strip", p->strip,
+        "interlace", p->interlace,
+        "filter", p->filter,
+        "palette", p->palette,
+        "Q", p->quality,
+        "dither", p->dither,
+        "effort", p->effort,
+        VIPS_SAVE_BITDEPTH(p),
+        NULL);
+}
+
+int vips_webpsave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, WebpSaveParams *p) {
+    return VIPS_SAVE_GO(webp, in, target, buf, len,
+        "Q", p->quality,
+        "strip", p->strip,
+        "lossless", p->lossless,
+        "near_lossless", p->near_lossless,
+        "smart_subsample", p->smart_subsample,
+        "alpha_q", p->alpha_quality,
+        "effort", p->effort,
+        "preset", p->preset,
+        NULL);
+}
+
+int vips_heifsave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, HeifSaveParams *p) {
+    return VIPS_SAVE_GO(heif, in, target, buf, len,
+        "Q", p->quality,
+        "strip", p->strip,
+        "lossless", p->lossless,
+        "compression", p->compression,
+        "effort", p->effort,
+        "subsample_mode", p->subsample_mode,
+        VIPS_SAVE_BITDEPTH(p),
+        NULL);
+}
+
+int vips_tiffsave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, TiffSaveParams *p) {
+#if VIPS_VERSION_AT_LEAST(8, 13)
+    return VIPS_SAVE_GO(tiff, in, target, buf, len,
+#else
+    if (target != NULL) {
+        void *tmp = NULL;
+        size_t tmp_len = 0;
+
+        if (vips_tiffsave_params_go(in, NULL, &tmp, &tmp_len, p))
+            return 1;
+
+        return vips_target_write_buffer_go(target, tmp, tmp_len);
+    }
+
+    return vips_tiffsave_buffer(in, buf, len,
+#endif
+        "Q", p->quality,
+        "strip", p->strip,
+        "compression", p->compression,
+        "predictor", p->predictor,
+        "tile", p->tile,
+        "tile_width", p->tile_width,
+        "tile_height", p->tile_height,
+        "pyramid", p->pyramid,
+        NULL);
+}
+
+// The savers read delay in milliseconds and loop, loaders before 8.9 set gif-delay in centiseconds and gif-loop only
+static int vips_gif_metadata_go(VipsImage *in, VipsImage **out) {
+    int val;
+
+    if (vips_copy(in, out, NULL))
+        return 1;
+
+    if (vips_image_get_typeof(*out, "delay") == G_TYPE_INVALID &&
+        vips_image_get_typeof(*out, "gif-delay") != G_TYPE_INVALID &&
+        !vips_image_get_int(*out, "gif-delay", &val)) {
+
+        int n = vips_image_get_n_pages(*out);
+        int *delay = g_new(int, n);
+
+        for (int i = 0; i < n; i++)
+            delay[i] = val * 10;
+
+        vips_image_set_array_int(*out, "delay", delay, n);
+        g_free(delay);
+    }
+
+    if (vips_image_get_typeof(*out, "loop") == G_TYPE_INVALID &&
+        vips_image_get_typeof(*out, "gif-loop") != G_TYPE_INVALID &&
+        !vips_image_get_int(*out, "gif-loop", &val)) {
+
+        vips_image_set_int(*out, "loop", val);
+    }
+
+    return 0;
+}
+
+// Native saver is used when libvips is built with cgif, image magick otherwise. Options are known
+// to the native saver only, NULL params keep the saver defaults.
+int vips_gifsave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, GifSaveParams *p) {
+    VipsImage *tmp;
+    int res;
+
+    if (vips_gif_metadata_go(in, &tmp))
+        return 1;
+
+#if VIPS_VERSION_AT_LEAST(8, 12)
+    if (vips_type_find("VipsOperation", "gifsave_buffer")) {
+        if (p == NULL) {
+            res = VIPS_SAVE_GO(gif, tmp, target, buf, len, NULL);
+        } else {
+            res = VIPS_SAVE_GO(gif, tmp, target, buf, len,
+                "dither", p->dither,
+                "effort", p->effort,
+                "bitdepth", p->bitdepth,
+#if VIPS_VERSION_AT_LEAST(8, 13)
+                "interframe_maxerror", p->interframe_maxerror,
+                "reuse", p->reuse,
+#endif
+                NULL);
+        }
+
+        clear_image_go(&tmp);
+        return res;
+    }
+#endif
+
+    if (target != NULL) {
+        void *tmp_buf = NULL;
+        size_t tmp_len = 0;
+
+        res = vips_magicksave_buffer(tmp, &tmp_buf, &tmp_len, "format", "gif", NULL);
+        clear_image_go(&tmp);
+        if (res)
+            return 1;
+
+        return vips_target_write_buffer_go(target, tmp_buf, tmp_len);
+    }
+
+    res = vips_magicksave_buffer(tmp, buf, len, "format", "gif", NULL);
+    clear_image_go(&tmp);
+
+    return res;
+}
+
+int vips_jxlsave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, JxlSaveParams *p) {
+#if VIPS_VERSION_AT_LEAST(8, 11)
+    if (!vips_operation_exists_go("jxlsave_buffer"))
+        return 1;
+
+    return VIPS_SAVE_GO(jxl, in, target, buf, len,
+        "distance", p->distance,
+        "effort", p->effort,
+        "lossless", p->lossless,
+        "tier", p->tier,
+        "strip", p->strip,
+        NULL);
+#else
+    vips_operation_exists_go("jxlsave_buffer");
+    return 1;
+#endif
+}
+
+int vips_jp2ksave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, Jp2kSaveParams *p) {
+#if VIPS_VERSION_AT_LEAST(8, 11)
+    if (!vips_operation_exists_go("jp2ksave_buffer"))
+        return 1;
+
+    return VIPS_SAVE_GO(jp2k, in, target, buf, len,
+        "tile_width", p->tile_width,
+        "tile_height", p->tile_height,
+        "lossless", p->lossless,
+        "Q", p->quality,
+        "subsample_mode", p->subsample_mode,
+        "strip", p->strip,
+        NULL);
+#else
+    vips_operation_exists_go("jp2ksave_buffer");
+    return 1;
+#endif
+}
+
+// 8-bit sRGB with alpha as packed bytes, used by the encoders written in Go
+unsigned char *vips_rgba_pixels_go(VipsImage *in, size_t *len) {
+    VipsImage *srgb, *rgba, *tmp;
+
+    if (vips_colourspace(in, &srgb, VIPS_INTERPRETATION_sRGB, NULL))
+        return NULL;
+
+    if (vips_ensure_alpha_go(srgb, &rgba)) {
+        clear_image_go(&srgb);
+        return NULL;
+    }
+    clear_image_go(&srgb);
+
+    if (vips_cast(rgba, &tmp, VIPS_FORMAT_UCHAR, NULL)) {
+        clear_image_go(&rgba);
+        return NULL;
+    }
+    clear_image_go(&rgba);
+
+    unsigned char *pixels = vips_image_write_to_memory(tmp, len);
+    clear_image_go(&tmp);
+
+    return pixels;
+}
+
+// Flattened image in Lab as packed floats, used to compare an image with its encoded copy
+float *vips_lab_pixels_go(VipsImage *in, size_t *len) {
+    VipsImage *flat, *lab, *tmp;
+
+    if (vips_image_hasalpha(in)) {
+        if (vips_flatten(in, &flat, NULL))
+            return NULL;
+    } else {
+        if (vips_copy(in, &flat, NULL))
+            return NULL;
+    }
+
+    if (vips_colourspace(flat, &lab, VIPS_INTERPRETATION_LAB, NULL)) {
+        clear_image_go(&flat);
+        return NULL;
+    }
+    clear_image_go(&flat);
+
+    if (vips_cast(lab, &tmp, VIPS_FORMAT_FLOAT, NULL)) {
+        clear_image_go(&lab);
+        return NULL;
+    }
+    clear_image_go(&lab);
+
+    float *pixels = vips_image_write_to_memory(tmp, len);
+    clear_image_go(&tmp);
+
+    return pixels;
+}
+
+// Rotates by any angle expanding the canvas. Images with alpha are premultiplied, so the background
+// must be premultiplied too.
+int vips_rotate_any_go(VipsImage *in, VipsImage **out, double angle, const char *interp, double *bg, int bgn) {
+    VipsBandFormat format = vips_band_format_go(in);
+    gboolean alpha = vips_image_hasalpha(in);
+    VipsInterpolate *interpolate;
+    VipsArrayDouble *background;
+    VipsImage *tmp1, *tmp2;
+    int res;
+
+    if (!(interpolate = vips_interpolate_new(interp))) {
+        vips_error("vips_rotate_any", "Unknown interpolator %s", interp);
+        return 1;
+    }
+
+    if (alpha)
+        res = vips_premultiply(in, &tmp1, NULL);
+    else
+        res = vips_copy(in, &tmp1, NULL);
+
+    if (res) {
+        g_object_unref(interpolate);
+        return 1;
+    }
+
+    background = vips_array_double_new(bg, bgn);
+    res = vips_similarity(tmp1, &tmp2, "angle", angle, "interpolate", interpolate, "background", background, NULL);
+
+    vips_area_unref((VipsArea *) background);
+    g_object_unref(interpolate);
+    clear_image_go(&tmp1);
+
+    if (res)
+        return 1;
+
+    if (alpha) {
+        if (vips_unpremultiply(tmp2, &tmp1, NULL)) {
+            clear_image_go(&tmp2);
+            return 1;
+        }
+        swap_and_clear_go(&tmp2, tmp1);
+    }
+
+    res = vips_cast(tmp2, out, format, NULL);
+    clear_image_go(&tmp2);
+
+    return res;
+}
+
 int vips_resize_with_premultiply_go(VipsImage *in, VipsImage **out, double scale) {
 	VipsBandFormat format;
     VipsImage *tmp1, *tmp2;
@@ -22,12 +22,19 @@ OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
 SOFTWARE.
 */
 
+#ifndef LIBVIPS_GO_H
+#define LIBVIPS_GO_H
+
 #include <stdlib.h>
+#include <stdint.h>
 
 #include <vips/vips.h>
 #include <vips/vips7compat.h>
 #include <vips/vector.h>
 
+#define VIPS_VERSION_AT_LEAST(major, minor) \
+    (VIPS_MAJOR_VERSION > (major) || (VIPS_MAJOR_VERSION == (major) && VIPS_MINOR_VERSION >= (minor)))
+
 enum ImageFormat {
     UNKNOWN = 0,
     AVIF,
@@ -40,13 +47,127 @@ enum ImageFormat {
   	PNG,
   	SVG,
   	TIFF,
-  	WEBP
+  	WEBP,
+  	JXL,
+  	JP2K
+};
+
+typedef struct _LoadParams {
+    int fail_on;
+    gboolean unlimited;
+    // Rasterisation of the vector formats
+    double dpi;
+    double scale;
+} LoadParams;
+
+enum ProgressStage {
+    PREEVAL = 0,
+    EVAL,
+    POSTEVAL
 };
 
+typedef struct _JpegSaveParams {
+    int quality;
+    gboolean strip;
+    gboolean interlace;
+    gboolean optimize_coding;
+    int subsample_mode;
+    gboolean trellis_quant;
+    gboolean overshoot_deringing;
+    gboolean optimize_scans;
+    int quant_table;
+} JpegSaveParams;
+
+typedef struct _PngSaveParams {
+    int compression;
+    gboolean strip;
+    gboolean interlace;
+    int filter;
+    gboolean palette;
+    int quality;
+    double dither;
+    int effort;
+    int bitdepth;
+} PngSaveParams;
+
+typedef struct _WebpSaveParams {
+    int quality;
+    gboolean strip;
+    gboolean lossless;
+    gboolean near_lossless;
+    gboolean smart_subsample;
+    int alpha_quality;
+    int effort;
+    int preset;
+} WebpSaveParams;
+
+typedef struct _HeifSaveParams {
+    int quality;
+    gboolean strip;
+    gboolean lossless;
+    int compression;
+    int effort;
+    int subsample_mode;
+    int bitdepth;
+} HeifSaveParams;
+
+typedef struct _TiffSaveParams {
+    int quality;
+    gboolean strip;
+    int compression;
+    int predictor;
+    gboolean tile;
+    int tile_width;
+    int tile_height;
+    gboolean pyramid;
+} TiffSaveParams;
+
+typedef struct _GifSaveParams {
+    double dither;
+    int effort;
+    int bitdepth;
+    double interframe_maxerror;
+    gboolean reuse;
+} GifSaveParams;
+
+typedef struct _JxlSaveParams {
+    double distance;
+    int effort;
+    gboolean lossless;
+    int tier;
+    gboolean strip;
+} JxlSaveParams;
+
+typedef struct _Jp2kSaveParams {
+    int tile_width;
+    int tile_height;
+    gboolean lossless;
+    int quality;
+    int subsample_mode;
+    gboolean strip;
+} Jp2kSaveParams;
+
+typedef struct _PdfLoadParams {
+    double dpi;
+    double scale;
+    double background[4];
+    const char *password;
+} PdfLoadParams;
+
 int vips_initialize_go();
-int vips_image_load_go(void *buf, size_t len, int imgtype, VipsImage **out);
-int vips_pdf_load_go(void *buf, size_t len, VipsImage **out, int page, int n);
+int vips_operation_block_go(const char *name);
+gboolean vips_operation_available_go(const char *name);
+int vips_image_load_go(void *buf, size_t len, int imgtype, LoadParams *params, VipsImage **out);
+int vips_pdf_load_go(void *buf, size_t len, VipsImage **out, int page, int n, LoadParams *params);
+int vips_pdf_load_page_go(void *buf, size_t len, VipsImage **out, int page, PdfLoadParams *p);
+int vips_jp2k_load_go(void *buf, size_t len, VipsImage **out, int page);
+int vips_image_load_source_go(VipsSource *source, LoadParams *params, VipsImage **out);
+VipsSource *vips_source_custom_new_go(uintptr_t handle);
+int vips_thumbnail_buffer_go(void *buf, size_t len, VipsImage **out, int width, int height, VipsSize size,
+                             VipsInteresting crop, gboolean linear, gboolean no_rotate, const char *import_profile,
+                             const char *export_profile, VipsIntent intent, int fail_on);
 VipsImage *vips_image_new_from_bytes_go(const void *data, size_t size, int width, int height);
+VipsImage *vips_image_new_from_rgba_go(const void *data, size_t size, int width, int height);
 
 VipsBandFormat vips_band_format_go(VipsImage *in);
 
@@ -56,6 +177,10 @@ void swap_and_clear_go(VipsImage **in, VipsImage *out);
 
 gboolean vips_is_animated_go(VipsImage * in);
 
+gulong vips_image_connect_eval_go(VipsImage *in, uintptr_t handle);
+void vips_image_disconnect_eval_go(VipsImage *in, gulong id);
+void vips_image_connect_progress_go(VipsImage *in, uintptr_t handle);
+
 int vips_get_orientation(VipsImage *image);
 int vips_addalpha_go(VipsImage *in, VipsImage **out);
 int vips_copy_go(VipsImage *in, VipsImage **out);
@@ -84,6 +209,34 @@ int vips_heifsave_go(VipsImage *in, void **buf, size_t *len, int quality, int co
 int vips_bmpsave_go(VipsImage *in, void **buf, size_t *len);
 int vips_pdfsave_go(VipsImage *in, void **buf, size_t *len);
 
+VipsTarget *vips_target_custom_new_go(uintptr_t handle);
+int vips_jpegsave_target_go(VipsImage *in, VipsTarget *target, int quality, int strip, int interlace);
+int vips_pngsave_target_go(VipsImage *in, VipsTarget *target, int compression, int strip, int interlace, int palette);
+int vips_webpsave_target_go(VipsImage *in, VipsTarget *target, int quality, int strip, int lossless);
+int vips_gifsave_target_go(VipsImage *in, VipsTarget *target);
+int vips_tiffsave_target_go(VipsImage *in, VipsTarget *target, int quality);
+int vips_avifsave_target_go(VipsImage *in, VipsTarget *target, int quality);
+int vips_heifsave_target_go(VipsImage *in, VipsTarget *target, int quality, int compression, int lossless);
+int vips_bmpsave_target_go(VipsImage *in, VipsTarget *target);
+int vips_pdfsave_target_go(VipsImage *in, VipsTarget *target);
+
+int vips_jpegsave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, JpegSaveParams *p);
+int vips_pngsave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, PngSaveParams *p);
+int vips_webpsave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, WebpSaveParams *p);
+int vips_heifsave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, HeifSaveParams *p);
+int vips_tiffsave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, TiffSaveParams *p);
+int vips_gifsave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, GifSaveParams *p);
+
+int vips_jxlsave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, JxlSaveParams *p);
+
+int vips_jp2ksave_params_go(VipsImage *in, VipsTarget *target, void **buf, size_t *len, Jp2kSaveParams *p);
+
+unsigned char *vips_rgba_pixels_go(VipsImage *in, size_t *len);
+float *vips_lab_pixels_go(VipsImage *in, size_t *len);
+
+int vips_rotate_any_go(VipsImage *in, VipsImage **out, double angle, const char *interp, double *bg, int bgn);
 int vips_resize_with_premultiply_go(VipsImage *in, VipsImage **out, double scale);
 
 int vips_arrayjoin_go(VipsImage **in, VipsImage **out, int n);
+
+#endif